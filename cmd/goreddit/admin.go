@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nahuakang/goreddit"
+)
+
+// admin runs the admin command with its arguments, which makes a user a site
+// admin or takes that away. Site admins can only be chosen here, so that no
+// request to the web server can make anyone an admin.
+func admin(store goreddit.Store, args []string) error {
+	if len(args) != 2 || (args[0] != "grant" && args[0] != "revoke") {
+		return fmt.Errorf("unknown admin command %q, want grant USERNAME or revoke USERNAME", strings.Join(args, " "))
+	}
+
+	ctx := context.Background()
+	u, err := store.UserByUsername(ctx, args[1])
+	if errors.Is(err, goreddit.ErrNotFound) {
+		return fmt.Errorf("there is no user called %q", args[1])
+	} else if err != nil {
+		return err
+	}
+
+	u.Admin = args[0] == "grant"
+	if err := store.UpdateUser(ctx, &u); err != nil {
+		return err
+	}
+
+	if u.Admin {
+		fmt.Printf("%s is a site admin\n", u.Username)
+	} else {
+		fmt.Printf("%s is no longer a site admin\n", u.Username)
+	}
+	return nil
+}
+
+// adminStore opens the store of the admin command. The in-memory store would
+// forget the change as soon as the command exits.
+func adminStore(db string, queryTimeout time.Duration) (goreddit.Store, error) {
+	if db == "memory://" {
+		return nil, errors.New("the admin command needs a postgres:// or sqlite:// database")
+	}
+	store, _, err := openStore(db, queryTimeout)
+	return store, err
+}
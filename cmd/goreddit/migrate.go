@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nahuakang/goreddit/postgres"
+)
+
+// migrate runs the migrate command with its arguments on the PostgreSQL
+// database. SQLite databases are migrated whenever they are opened.
+func migrate(db string, args []string) error {
+	if !strings.HasPrefix(db, "postgres://") && !strings.HasPrefix(db, "postgresql://") {
+		return fmt.Errorf("only PostgreSQL databases need migrating, not %q", db)
+	}
+	if len(args) == 0 {
+		return errors.New("missing migrate command, want up, down, status or to VERSION")
+	}
+
+	m, err := postgres.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	ctx := context.Background()
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		err = m.Up(ctx)
+	case args[0] == "down" && len(args) == 1:
+		err = m.Down(ctx)
+	case args[0] == "to" && len(args) == 2:
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		err = m.To(ctx, version)
+	case args[0] == "status" && len(args) == 1:
+		return status(ctx, m)
+	default:
+		return fmt.Errorf("unknown migrate command %q, want up, down, status or to VERSION", strings.Join(args, " "))
+	}
+	if err != nil {
+		return err
+	}
+	return status(ctx, m)
+}
+
+// status prints the schema version and which migrations are applied
+func status(ctx context.Context, m *postgres.Migrator) error {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	state := ""
+	switch {
+	case dirty:
+		state = " (dirty)"
+	case version < m.Latest():
+		state = " (behind)"
+	}
+	fmt.Printf("Schema version %d of %d%s\n", version, m.Latest(), state)
+	for _, mig := range m.Migrations() {
+		mark := " "
+		if mig.Version <= version {
+			mark = "x"
+		}
+		fmt.Printf("  [%s] %d_%s\n", mark, mig.Version, mig.Name)
+	}
+	return nil
+}
+
+// checkSchema refuses PostgreSQL databases whose schema is behind the
+// migrations embedded in goreddit
+func checkSchema(db string) error {
+	m, err := postgres.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	version, dirty, err := m.Version(context.Background())
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("the database schema version %d is dirty, repair it by hand", version)
+	}
+	if version < m.Latest() {
+		return fmt.Errorf("the database schema is at version %d but goreddit needs version %d, run goreddit migrate up", version, m.Latest())
+	}
+	return nil
+}
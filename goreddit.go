@@ -1,64 +1,329 @@
 package goreddit
 
-import "github.com/google/uuid"
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // Thread is the basic struct for a thread
 type Thread struct {
-	ID          uuid.UUID `db:"id"`
-	Title       string    `db:"title"`
-	Description string    `db:"description"`
+	ID          uuid.UUID     `db:"id"`
+	UserID      uuid.NullUUID `db:"user_id"`
+	Title       string        `db:"title"`
+	Description string        `db:"description"`
+	Author      string        `db:"author"`
+	CreatedAt   time.Time     `db:"created_at"`
+	UpdatedAt   time.Time     `db:"updated_at"`
 }
 
 // Post is the basic struct for a post
 type Post struct {
-	ID            uuid.UUID `db:"id"`
-	ThreadID      uuid.UUID `db:"thread_id"`
-	Title         string    `db:"title"`
-	Content       string    `db:"content"`
-	Votes         int       `db:"votes"`
-	CommentsCount int       `db:"comments_count"`
-	ThreadTitle   string    `db:"thread_title"`
+	ID            uuid.UUID     `db:"id"`
+	ThreadID      uuid.UUID     `db:"thread_id"`
+	UserID        uuid.NullUUID `db:"user_id"`
+	Title         string        `db:"title"`
+	Content       string        `db:"content"`
+	Votes         int           `db:"votes"`
+	CommentsCount int           `db:"comments_count"`
+	ThreadTitle   string        `db:"thread_title"`
+	Author        string        `db:"author"`
+	CreatedAt     time.Time     `db:"created_at"`
+	UpdatedAt     time.Time     `db:"updated_at"`
 }
 
 // Comment is the basic struct for a comment
 type Comment struct {
-	ID      uuid.UUID `db:"id"`
-	PostID  uuid.UUID `db:"post_id"`
-	Content string    `db:"content"`
-	Votes   int       `db:"votes"`
+	ID           uuid.UUID     `db:"id"`
+	PostID       uuid.UUID     `db:"post_id"`
+	ParentID     uuid.NullUUID `db:"parent_id"`
+	UserID       uuid.NullUUID `db:"user_id"`
+	Content      string        `db:"content"`
+	Votes        int           `db:"votes"`
+	Author       string        `db:"author"`
+	CreatedAt    time.Time     `db:"created_at"`
+	UpdatedAt    time.Time     `db:"updated_at"`
+	Depth        int           `db:"depth"`
+	RepliesCount int           `db:"replies_count"`
+}
+
+// User is the basic struct for a user
+type User struct {
+	ID       uuid.UUID `db:"id"`
+	Username string    `db:"username"`
+	Password string    `db:"password"`
+	// Admin makes the user a site admin, who may do anything in any thread
+	Admin bool `db:"admin"`
+}
+
+// Role is what a user is allowed to do in a thread
+type Role string
+
+// Roles of users, from the least to the most privileged
+const (
+	// RoleGuest is the role of visitors who are not logged in
+	RoleGuest Role = ""
+	// RoleBanned is the role of users who were banned from the thread
+	RoleBanned Role = "banned"
+	RoleUser   Role = "user"
+	// RoleModerator is the role of the moderators of the thread
+	RoleModerator Role = "moderator"
+	// RoleAdmin is the role of site admins in every thread
+	RoleAdmin Role = "admin"
+)
+
+// Permission is an action that only some roles may take
+type Permission string
+
+// Permissions checked before changing threads, posts and comments
+const (
+	PermCreateThread Permission = "create_thread"
+	// PermPost allows creating posts and comments and voting in the thread
+	PermPost             Permission = "post"
+	PermEditPost         Permission = "edit_post"
+	PermEditComment      Permission = "edit_comment"
+	PermRemovePost       Permission = "remove_post"
+	PermRemoveComment    Permission = "remove_comment"
+	PermBanUser          Permission = "ban_user"
+	PermManageModerators Permission = "manage_moderators"
+	PermDeleteThread     Permission = "delete_thread"
+)
+
+// rolePermissions lists the permissions of every role. Authors may edit and
+// delete their own threads, posts and comments unless they were banned from
+// the thread; the edit and remove permissions cover content of other users.
+var rolePermissions = map[Role][]Permission{
+	RoleBanned: {PermCreateThread},
+	RoleUser:   {PermCreateThread, PermPost},
+	RoleModerator: {PermCreateThread, PermPost, PermEditPost, PermEditComment, PermRemovePost, PermRemoveComment,
+		PermBanUser, PermManageModerators},
+	RoleAdmin: {PermCreateThread, PermPost, PermEditPost, PermEditComment, PermRemovePost, PermRemoveComment,
+		PermBanUser, PermManageModerators, PermDeleteThread},
 }
 
+// Can reports whether the role has the permission
+func (r Role) Can(p Permission) bool {
+	for _, rp := range rolePermissions[r] {
+		if rp == p {
+			return true
+		}
+	}
+	return false
+}
+
+// PostView is everything shown on the page of a post: the post, its thread
+// and a page of its comment tree
+type PostView struct {
+	Post     Post
+	Thread   Thread
+	Comments []Comment
+	PageInfo PageInfo
+}
+
+// SearchResult is a thread, post or comment matching a search query
+type SearchResult struct {
+	Kind     string        `db:"kind"` // "thread", "post" or "comment"
+	ID       uuid.UUID     `db:"id"`
+	ThreadID uuid.UUID     `db:"thread_id"`
+	PostID   uuid.NullUUID `db:"post_id"`
+	Title    string        `db:"title"`
+	Snippet  string        `db:"snippet"`
+	Rank     float64       `db:"rank"`
+}
+
+// Markers around the matching words in the Title and Snippet of a SearchResult
+const (
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
+
+// Sort is the order in which posts are listed
+type Sort string
+
+// Sort orders for post listings
+const (
+	SortHot           Sort = "hot"
+	SortNew           Sort = "new"
+	SortTop           Sort = "top"
+	SortControversial Sort = "controversial"
+)
+
+// Period restricts top and controversial post listings to recent posts
+type Period string
+
+// Periods for top and controversial post listings
+const (
+	PeriodHour  Period = "hour"
+	PeriodDay   Period = "day"
+	PeriodWeek  Period = "week"
+	PeriodMonth Period = "month"
+	PeriodYear  Period = "year"
+	PeriodAll   Period = "all"
+)
+
+// PostSort combines the sort order and period of a post listing
+type PostSort struct {
+	Sort   Sort
+	Period Period
+}
+
+// DefaultPageLimit is the number of items on a page if Page.Limit is not set
+const DefaultPageLimit = 25
+
+// Page selects a page of a listing. After and Before are cursors taken from a
+// PageInfo and at most one of them is set; both are empty for the first page.
+type Page struct {
+	After  string
+	Before string
+	Limit  int
+}
+
+// PageInfo contains the cursors of the pages next to a listing page. A cursor
+// is empty if there is no such page.
+type PageInfo struct {
+	Next string
+	Prev string
+}
+
+// Cursor is the position of an item in a listing sorted by a numeric key and
+// the item id, both descending. The stores hand it out in its encoded form.
+type Cursor struct {
+	Key float64
+	ID  uuid.UUID
+}
+
+// Encode returns the opaque string form of the cursor
+func (c Cursor) Encode() string {
+	s := strconv.FormatFloat(c.Key, 'g', -1, 64) + "," + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// DecodeCursor parses a cursor returned by Cursor.Encode. It returns
+// ErrInvalidCursor if s is not such a cursor.
+func DecodeCursor(s string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(b), ",", 2)
+	if len(parts) != 2 {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	key, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor{Key: key, ID: id}, nil
+}
+
+// Errors returned by the stores, possibly wrapped, so that callers can tell
+// failures apart with errors.Is
+var (
+	// ErrNotFound means that the requested thread, post, comment or user
+	// does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means that the data clashes with existing data, such as a
+	// username that is already taken
+	ErrConflict = errors.New("conflict")
+	// ErrInvalidCursor means that the After or Before cursor of a Page was
+	// not handed out by a store, for example because it was edited in a URL
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
 // ThreadStore is the basic interface for postgres.ThreadStore
 type ThreadStore interface {
-	Thread(id uuid.UUID) (Thread, error)
-	Threads() ([]Thread, error)
-	CreateThread(t *Thread) error
-	UpdateThread(t *Thread) error
-	DeleteThread(id uuid.UUID) error
+	Thread(ctx context.Context, id uuid.UUID) (Thread, error)
+	Threads(ctx context.Context, page Page) ([]Thread, PageInfo, error)
+	CreateThread(ctx context.Context, t *Thread) error
+	UpdateThread(ctx context.Context, t *Thread) error
+	DeleteThread(ctx context.Context, id uuid.UUID) error
 }
 
 // PostStore is the basic interface for postgres.ostStore
+//
+// PostView gets a post with its thread and the comments that CommentTree
+// returns for the other arguments, which the SQL stores do in one query.
 type PostStore interface {
-	Post(id uuid.UUID) (Post, error)
-	Posts() ([]Post, error)
-	PostsByThread(threadID uuid.UUID) ([]Post, error)
-	CreatePost(p *Post) error
-	UpdatePost(p *Post) error
-	DeletePost(id uuid.UUID) error
+	Post(ctx context.Context, id uuid.UUID) (Post, error)
+	Posts(ctx context.Context, sort PostSort, page Page) ([]Post, PageInfo, error)
+	PostsByThread(ctx context.Context, threadID uuid.UUID, sort PostSort, page Page) ([]Post, PageInfo, error)
+	PostView(ctx context.Context, id uuid.UUID, rootID uuid.NullUUID, sort Sort, maxDepth int, page Page) (PostView, error)
+	CreatePost(ctx context.Context, p *Post) error
+	UpdatePost(ctx context.Context, p *Post) error
+	DeletePost(ctx context.Context, id uuid.UUID) error
 }
 
 // CommentStore is the basic interface for postgres.CommentStore
 type CommentStore interface {
-	Comment(id uuid.UUID) (Comment, error)
-	CommentsByPost(postID uuid.UUID) ([]Comment, error)
-	CreateComment(c *Comment) error
-	UpdateComment(c *Comment) error
-	DeleteComment(id uuid.UUID) error
+	Comment(ctx context.Context, id uuid.UUID) (Comment, error)
+	CommentsByPost(ctx context.Context, postID uuid.UUID, page Page) ([]Comment, PageInfo, error)
+	CommentTree(ctx context.Context, postID uuid.UUID, rootID uuid.NullUUID, sort Sort, maxDepth int, page Page) ([]Comment, PageInfo, error)
+	CreateComment(ctx context.Context, c *Comment) error
+	UpdateComment(ctx context.Context, c *Comment) error
+	DeleteComment(ctx context.Context, id uuid.UUID) error
+}
+
+// UserStore is the basic interface for postgres.UserStore
+type UserStore interface {
+	User(ctx context.Context, id uuid.UUID) (User, error)
+	UserByUsername(ctx context.Context, username string) (User, error)
+	CreateUser(ctx context.Context, u *User) error
+	UpdateUser(ctx context.Context, u *User) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+}
+
+// VoteStore is the basic interface for postgres.VoteStore
+//
+// A vote value is -1 for a downvote, 1 for an upvote and 0 for no vote.
+type VoteStore interface {
+	VotePost(ctx context.Context, userID, postID uuid.UUID, value int) error
+	VoteComment(ctx context.Context, userID, commentID uuid.UUID, value int) error
+	PostVotes(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]int, error)
+	CommentVotes(ctx context.Context, userID uuid.UUID, commentIDs []uuid.UUID) (map[uuid.UUID]int, error)
+}
+
+// ModeratorStore is the basic interface for postgres.ModeratorStore
+//
+// Role returns the role of the user in the thread: RoleAdmin for site admins,
+// then RoleBanned, RoleModerator or RoleUser. Threads that do not exist, such
+// as uuid.Nil, give the role of the user outside of any thread. The author of
+// a thread becomes its moderator when the thread is created.
+type ModeratorStore interface {
+	Role(ctx context.Context, userID, threadID uuid.UUID) (Role, error)
+	Moderators(ctx context.Context, threadID uuid.UUID) ([]User, error)
+	AddModerator(ctx context.Context, threadID, userID uuid.UUID) error
+	RemoveModerator(ctx context.Context, threadID, userID uuid.UUID) error
+	BannedUsers(ctx context.Context, threadID uuid.UUID) ([]User, error)
+	BanUser(ctx context.Context, threadID, userID uuid.UUID) error
+	UnbanUser(ctx context.Context, threadID, userID uuid.UUID) error
+}
+
+// SearchStore is the basic interface for postgres.SearchStore
+//
+// If threadID is valid only the thread and its posts and comments are searched.
+type SearchStore interface {
+	Search(ctx context.Context, query string, threadID uuid.NullUUID, page Page) ([]SearchResult, PageInfo, error)
 }
 
-// Store is the wrapper for ThreadStore, PostStore, and CommentStore interfaces
+// Store is the wrapper for ThreadStore, PostStore, CommentStore, UserStore, VoteStore, ModeratorStore, and SearchStore interfaces
 type Store interface {
 	ThreadStore
 	PostStore
 	CommentStore
+	UserStore
+	VoteStore
+	ModeratorStore
+	SearchStore
 }
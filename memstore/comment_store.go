@@ -0,0 +1,215 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// Comment gets a comment based on id input
+func (s *Store) Comment(ctx context.Context, id uuid.UUID) (goreddit.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.comments[id]
+	if !ok {
+		return goreddit.Comment{}, fmt.Errorf("Error getting comment: %w", goreddit.ErrNotFound)
+	}
+	return c, nil
+}
+
+// CommentsByPost gets a page of the comments of a post, most votes first
+func (s *Store) CommentsByPost(ctx context.Context, postID uuid.UUID, page goreddit.Page) ([]goreddit.Comment, goreddit.PageInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var all []goreddit.Comment
+	var keys []goreddit.Cursor
+	for _, c := range s.comments {
+		if c.PostID != postID {
+			continue
+		}
+		all = append(all, c)
+		keys = append(keys, goreddit.Cursor{Key: float64(c.Votes), ID: c.ID})
+	}
+
+	idx, info, err := pageOf(page, keys)
+	if err != nil {
+		return []goreddit.Comment{}, goreddit.PageInfo{}, fmt.Errorf("Error getting comments: %w", err)
+	}
+
+	cc := make([]goreddit.Comment, 0, len(idx))
+	for _, i := range idx {
+		c := all[i]
+		c.Author = s.author(c.UserID)
+		cc = append(cc, c)
+	}
+	return cc, info, nil
+}
+
+// CommentTree gets the comments of a post in display order, where every
+// comment is followed by its replies. Siblings are sorted by sort and only
+// comments up to maxDepth levels deep are returned. If rootID is valid the
+// tree starts at that comment instead of the top-level comments of the post.
+// The page selects the top-level comments of the tree.
+func (s *Store) CommentTree(ctx context.Context, postID uuid.UUID, rootID uuid.NullUUID, sort goreddit.Sort, maxDepth int, page goreddit.Page) ([]goreddit.Comment, goreddit.PageInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cc, info, err := s.commentTree(postID, rootID, sort, maxDepth, page)
+	if err != nil {
+		return []goreddit.Comment{}, goreddit.PageInfo{}, fmt.Errorf("Error getting comments: %w", err)
+	}
+	return cc, info, nil
+}
+
+// commentTree gets the comments of CommentTree. The caller must hold the
+// lock.
+func (s *Store) commentTree(postID uuid.UUID, rootID uuid.NullUUID, sort goreddit.Sort, maxDepth int, page goreddit.Page) ([]goreddit.Comment, goreddit.PageInfo, error) {
+	// Group the comments of the post by their parent
+	replies := map[uuid.NullUUID][]goreddit.Comment{}
+	for _, c := range s.comments {
+		if c.PostID == postID {
+			replies[c.ParentID] = append(replies[c.ParentID], c)
+		}
+	}
+
+	var roots []goreddit.Comment
+	if rootID.Valid {
+		if c, ok := s.comments[rootID.UUID]; ok && c.PostID == postID {
+			roots = []goreddit.Comment{c}
+		}
+	} else {
+		roots = replies[uuid.NullUUID{}]
+	}
+
+	keys := make([]goreddit.Cursor, len(roots))
+	for i, c := range roots {
+		keys[i] = goreddit.Cursor{Key: commentSortKey(sort, c), ID: c.ID}
+	}
+	idx, info, err := pageOf(page, keys)
+	if err != nil {
+		return nil, goreddit.PageInfo{}, err
+	}
+
+	cc := []goreddit.Comment{}
+	var walk func(c goreddit.Comment, depth int)
+	walk = func(c goreddit.Comment, depth int) {
+		children := replies[uuid.NullUUID{UUID: c.ID, Valid: true}]
+		c.Author = s.author(c.UserID)
+		c.Depth = depth
+		c.RepliesCount = len(children)
+		cc = append(cc, c)
+		if depth+1 >= maxDepth {
+			return
+		}
+
+		keys := make([]goreddit.Cursor, len(children))
+		for i, child := range children {
+			keys[i] = goreddit.Cursor{Key: commentSortKey(sort, child), ID: child.ID}
+		}
+		for _, i := range sortDesc(keys) {
+			walk(children[i], depth+1)
+		}
+	}
+	for _, i := range idx {
+		walk(roots[i], 0)
+	}
+	return cc, info, nil
+}
+
+// commentSortKey returns the key that sibling comments are sorted by in
+// descending order, with ties broken by the comment id
+func commentSortKey(sort goreddit.Sort, c goreddit.Comment) float64 {
+	if sort == goreddit.SortNew {
+		return epoch(c.CreatedAt)
+	}
+	return float64(c.Votes)
+}
+
+// CreateComment creates a new comment
+func (s *Store) CreateComment(ctx context.Context, c *goreddit.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.posts[c.PostID]; !ok {
+		return fmt.Errorf("Error creating comment: %w", goreddit.ErrNotFound)
+	}
+	if _, ok := s.comments[c.ParentID.UUID]; c.ParentID.Valid && !ok {
+		return fmt.Errorf("Error creating comment: %w", goreddit.ErrNotFound)
+	}
+	if _, ok := s.comments[c.ID]; ok {
+		return fmt.Errorf("Error creating comment: %w", goreddit.ErrConflict)
+	}
+
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	s.comments[c.ID] = storedComment(*c)
+	return nil
+}
+
+// UpdateComment updates a comment. Its votes are left alone, as only votes
+// may change them.
+func (s *Store) UpdateComment(ctx context.Context, c *goreddit.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.comments[c.ID]
+	if !ok {
+		return fmt.Errorf("Error updating comment: %w", goreddit.ErrNotFound)
+	}
+	if _, ok := s.posts[c.PostID]; !ok {
+		return fmt.Errorf("Error updating comment: %w", goreddit.ErrNotFound)
+	}
+
+	stored.PostID = c.PostID
+	stored.Content = c.Content
+	stored.UpdatedAt = time.Now()
+	s.comments[c.ID] = stored
+
+	c.Votes = stored.Votes
+	c.ParentID = stored.ParentID
+	c.UserID = stored.UserID
+	c.CreatedAt = stored.CreatedAt
+	c.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// DeleteComment deletes a comment with its replies
+func (s *Store) DeleteComment(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.comments[id]; !ok {
+		return fmt.Errorf("Error deleting comment: %w", goreddit.ErrNotFound)
+	}
+	s.deleteComment(id)
+	return nil
+}
+
+// deleteComment deletes a comment with its replies and votes. The caller
+// must hold the lock.
+func (s *Store) deleteComment(id uuid.UUID) {
+	delete(s.comments, id)
+	for v := range s.commentVotes {
+		if v.targetID == id {
+			delete(s.commentVotes, v)
+		}
+	}
+	for _, c := range s.comments {
+		if c.ParentID.Valid && c.ParentID.UUID == id {
+			s.deleteComment(c.ID)
+		}
+	}
+}
+
+// storedComment returns c without the fields that are computed when reading
+func storedComment(c goreddit.Comment) goreddit.Comment {
+	c.Author = ""
+	c.Depth = 0
+	c.RepliesCount = 0
+	return c
+}
@@ -0,0 +1,121 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// Role gets the role of a user in a thread
+func (s *Store) Role(ctx context.Context, userID, threadID uuid.UUID) (goreddit.Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[userID]
+	switch {
+	case !ok:
+		return goreddit.RoleGuest, fmt.Errorf("Error getting role: %w", goreddit.ErrNotFound)
+	case u.Admin:
+		return goreddit.RoleAdmin, nil
+	case s.bans[member{threadID, userID}]:
+		return goreddit.RoleBanned, nil
+	case s.moderators[member{threadID, userID}]:
+		return goreddit.RoleModerator, nil
+	}
+	return goreddit.RoleUser, nil
+}
+
+// Moderators gets the moderators of a thread ordered by username
+func (s *Store) Moderators(ctx context.Context, threadID uuid.UUID) ([]goreddit.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.members(s.moderators, threadID), nil
+}
+
+// AddModerator makes a user a moderator of a thread
+func (s *Store) AddModerator(ctx context.Context, threadID, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.addMember(s.moderators, threadID, userID); err != nil {
+		return fmt.Errorf("Error adding moderator: %w", err)
+	}
+	return nil
+}
+
+// RemoveModerator removes a user from the moderators of a thread
+func (s *Store) RemoveModerator(ctx context.Context, threadID, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.moderators[member{threadID, userID}] {
+		return fmt.Errorf("Error removing moderator: %w", goreddit.ErrNotFound)
+	}
+	delete(s.moderators, member{threadID, userID})
+	return nil
+}
+
+// BannedUsers gets the users banned from a thread ordered by username
+func (s *Store) BannedUsers(ctx context.Context, threadID uuid.UUID) ([]goreddit.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.members(s.bans, threadID), nil
+}
+
+// BanUser bans a user from a thread
+func (s *Store) BanUser(ctx context.Context, threadID, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.addMember(s.bans, threadID, userID); err != nil {
+		return fmt.Errorf("Error banning user: %w", err)
+	}
+	return nil
+}
+
+// UnbanUser lifts the ban of a user from a thread
+func (s *Store) UnbanUser(ctx context.Context, threadID, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.bans[member{threadID, userID}] {
+		return fmt.Errorf("Error unbanning user: %w", goreddit.ErrNotFound)
+	}
+	delete(s.bans, member{threadID, userID})
+	return nil
+}
+
+// members gets the users listed for a thread in list ordered by username.
+// The caller must hold the lock.
+func (s *Store) members(list map[member]bool, threadID uuid.UUID) []goreddit.User {
+	uu := []goreddit.User{}
+	for m := range list {
+		if m.threadID == threadID {
+			uu = append(uu, s.users[m.userID])
+		}
+	}
+	sort.Slice(uu, func(i, j int) bool { return uu[i].Username < uu[j].Username })
+	return uu
+}
+
+// addMember lists a user for a thread in list, like the foreign keys and
+// primary key of the tables of the postgres package would. The caller must
+// hold the lock.
+func (s *Store) addMember(list map[member]bool, threadID, userID uuid.UUID) error {
+	if _, ok := s.threads[threadID]; !ok {
+		return goreddit.ErrNotFound
+	}
+	if _, ok := s.users[userID]; !ok {
+		return goreddit.ErrNotFound
+	}
+	if list[member{threadID, userID}] {
+		return goreddit.ErrConflict
+	}
+	list[member{threadID, userID}] = true
+	return nil
+}
@@ -0,0 +1,95 @@
+package memstore
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/nahuakang/goreddit"
+)
+
+// before reports whether c comes before o in a descending listing
+func before(c, o goreddit.Cursor) bool {
+	if c.Key != o.Key {
+		return c.Key > o.Key
+	}
+	return bytes.Compare(c.ID[:], o.ID[:]) > 0
+}
+
+// sortDesc returns the indexes into keys in descending order of the keys
+func sortDesc(keys []goreddit.Cursor) []int {
+	idx := make([]int, len(keys))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool {
+		return before(keys[idx[a]], keys[idx[b]])
+	})
+	return idx
+}
+
+// pageOf returns the indexes into keys of the items on the page, in
+// descending order of the keys, and the cursors of the neighbouring pages
+func pageOf(page goreddit.Page, keys []goreddit.Cursor) ([]int, goreddit.PageInfo, error) {
+	idx := sortDesc(keys)
+	sorted := make([]goreddit.Cursor, len(idx))
+	for i, j := range idx {
+		sorted[i] = keys[j]
+	}
+
+	from, to, info, err := paginate(page, sorted)
+	if err != nil {
+		return nil, goreddit.PageInfo{}, err
+	}
+	return idx[from:to], info, nil
+}
+
+// paginate returns the range of keys, which must be sorted in descending
+// order, that belongs to the page and the cursors of the neighbouring pages
+func paginate(page goreddit.Page, keys []goreddit.Cursor) (from, to int, info goreddit.PageInfo, err error) {
+	limit := page.Limit
+	if limit <= 0 {
+		limit = goreddit.DefaultPageLimit
+	}
+
+	switch {
+	case page.Before != "":
+		c, err := goreddit.DecodeCursor(page.Before)
+		if err != nil {
+			return 0, 0, info, err
+		}
+		to = sort.Search(len(keys), func(i int) bool { return !before(keys[i], c) })
+		from = to - limit
+		if from < 0 {
+			from = 0
+		}
+		if from == to {
+			return from, to, info, nil
+		}
+		info.Next = keys[to-1].Encode()
+		if from > 0 {
+			info.Prev = keys[from].Encode()
+		}
+	default:
+		if page.After != "" {
+			c, err := goreddit.DecodeCursor(page.After)
+			if err != nil {
+				return 0, 0, info, err
+			}
+			from = sort.Search(len(keys), func(i int) bool { return before(c, keys[i]) })
+		}
+		to = from + limit
+		if to > len(keys) {
+			to = len(keys)
+		}
+		if from == to {
+			return from, to, info, nil
+		}
+		if to < len(keys) {
+			info.Next = keys[to-1].Encode()
+		}
+		if page.After != "" {
+			info.Prev = keys[from].Encode()
+		}
+	}
+	return from, to, info, nil
+}
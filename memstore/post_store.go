@@ -0,0 +1,258 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// Post gets a post based on id input
+func (s *Store) Post(ctx context.Context, id uuid.UUID) (goreddit.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.posts[id]
+	if !ok {
+		return goreddit.Post{}, fmt.Errorf("Error getting post: %w", goreddit.ErrNotFound)
+	}
+	p.Author = s.author(p.UserID)
+	return p, nil
+}
+
+// PostView gets a post with its thread and a page of its comment tree
+func (s *Store) PostView(ctx context.Context, id uuid.UUID, rootID uuid.NullUUID, sort goreddit.Sort, maxDepth int, page goreddit.Page) (goreddit.PostView, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.posts[id]
+	if !ok {
+		return goreddit.PostView{}, fmt.Errorf("Error getting post: %w", goreddit.ErrNotFound)
+	}
+	p.Author = s.author(p.UserID)
+
+	t := s.threads[p.ThreadID]
+	t.Author = s.author(t.UserID)
+
+	cc, info, err := s.commentTree(id, rootID, sort, maxDepth, page)
+	if err != nil {
+		return goreddit.PostView{}, fmt.Errorf("Error getting post: %w", err)
+	}
+	return goreddit.PostView{Post: p, Thread: t, Comments: cc, PageInfo: info}, nil
+}
+
+// PostsByThread gets a page of the posts of a thread
+func (s *Store) PostsByThread(ctx context.Context, threadID uuid.UUID, sort goreddit.PostSort, page goreddit.Page) ([]goreddit.Post, goreddit.PageInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pp, info, err := s.postPage(sort, page, func(p goreddit.Post) bool { return p.ThreadID == threadID })
+	if err != nil {
+		return []goreddit.Post{}, goreddit.PageInfo{}, fmt.Errorf("Error getting posts: %w", err)
+	}
+	return pp, info, nil
+}
+
+// Posts gets a page of the posts of all threads
+func (s *Store) Posts(ctx context.Context, sort goreddit.PostSort, page goreddit.Page) ([]goreddit.Post, goreddit.PageInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pp, info, err := s.postPage(sort, page, func(p goreddit.Post) bool { return true })
+	if err != nil {
+		return []goreddit.Post{}, goreddit.PageInfo{}, fmt.Errorf("Error getting posts: %w", err)
+	}
+	for i := range pp {
+		pp[i].ThreadTitle = s.threads[pp[i].ThreadID].Title
+	}
+	return pp, info, nil
+}
+
+// postPage gets the page of the posts matching the filter in the order of
+// sort. The caller must hold the lock.
+func (s *Store) postPage(sort goreddit.PostSort, page goreddit.Page, filter func(goreddit.Post) bool) ([]goreddit.Post, goreddit.PageInfo, error) {
+	since, limited := postPeriodStart(sort)
+
+	var all []goreddit.Post
+	var keys []goreddit.Cursor
+	for _, p := range s.posts {
+		if !filter(p) || (limited && !p.CreatedAt.After(since)) {
+			continue
+		}
+		all = append(all, p)
+		keys = append(keys, goreddit.Cursor{Key: s.postSortKey(sort, p), ID: p.ID})
+	}
+
+	idx, info, err := pageOf(page, keys)
+	if err != nil {
+		return nil, goreddit.PageInfo{}, err
+	}
+
+	pp := make([]goreddit.Post, 0, len(idx))
+	for _, i := range idx {
+		p := all[i]
+		p.Author = s.author(p.UserID)
+		p.CommentsCount = s.commentsCount(p.ID)
+		pp = append(pp, p)
+	}
+	return pp, info, nil
+}
+
+// postPeriodStart returns the time after which posts must have been created
+// to appear in top and controversial listings, if those are limited
+func postPeriodStart(sort goreddit.PostSort) (time.Time, bool) {
+	if sort.Sort != goreddit.SortTop && sort.Sort != goreddit.SortControversial {
+		return time.Time{}, false
+	}
+
+	now := time.Now()
+	switch sort.Period {
+	case goreddit.PeriodHour:
+		return now.Add(-time.Hour), true
+	case goreddit.PeriodDay:
+		return now.AddDate(0, 0, -1), true
+	case goreddit.PeriodWeek:
+		return now.AddDate(0, 0, -7), true
+	case goreddit.PeriodMonth:
+		return now.AddDate(0, -1, 0), true
+	case goreddit.PeriodYear:
+		return now.AddDate(-1, 0, 0), true
+	}
+	return time.Time{}, false
+}
+
+// postSortKey returns the key that post listings are sorted by in descending
+// order, using the same formulas as the postgres package. The caller must
+// hold the lock.
+func (s *Store) postSortKey(sort goreddit.PostSort, p goreddit.Post) float64 {
+	switch sort.Sort {
+	case goreddit.SortNew:
+		return epoch(p.CreatedAt)
+	case goreddit.SortTop:
+		return float64(p.Votes)
+	case goreddit.SortControversial:
+		// Many votes that are evenly split rank highest
+		var ups, downs float64
+		for v, value := range s.postVotes {
+			if v.targetID != p.ID {
+				continue
+			}
+			if value == 1 {
+				ups++
+			} else if value == -1 {
+				downs++
+			}
+		}
+		if ups == 0 || downs == 0 {
+			return 0
+		}
+		return math.Pow(ups+downs, math.Min(ups, downs)/math.Max(ups, downs))
+	default:
+		// Logarithmic score plus a bonus for newer posts, where every 12.5
+		// hours weigh as much as ten times the votes
+		votes := float64(p.Votes)
+		sign := 0.0
+		if votes > 0 {
+			sign = 1
+		} else if votes < 0 {
+			sign = -1
+		}
+		return sign*math.Log10(math.Max(math.Abs(votes), 1)) + epoch(p.CreatedAt)/45000
+	}
+}
+
+// commentsCount returns the number of comments on a post. The caller must
+// hold the lock.
+func (s *Store) commentsCount(postID uuid.UUID) int {
+	n := 0
+	for _, c := range s.comments {
+		if c.PostID == postID {
+			n++
+		}
+	}
+	return n
+}
+
+// CreatePost creates a post
+func (s *Store) CreatePost(ctx context.Context, p *goreddit.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.threads[p.ThreadID]; !ok {
+		return fmt.Errorf("Error creating post: %w", goreddit.ErrNotFound)
+	}
+	if _, ok := s.posts[p.ID]; ok {
+		return fmt.Errorf("Error creating post: %w", goreddit.ErrConflict)
+	}
+
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+	s.posts[p.ID] = storedPost(*p)
+	return nil
+}
+
+// UpdatePost updates a post. Its votes are left alone, as only votes may
+// change them.
+func (s *Store) UpdatePost(ctx context.Context, p *goreddit.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.posts[p.ID]
+	if !ok {
+		return fmt.Errorf("Error updating post: %w", goreddit.ErrNotFound)
+	}
+	if _, ok := s.threads[p.ThreadID]; !ok {
+		return fmt.Errorf("Error updating post: %w", goreddit.ErrNotFound)
+	}
+
+	stored.ThreadID = p.ThreadID
+	stored.Title = p.Title
+	stored.Content = p.Content
+	stored.UpdatedAt = time.Now()
+	s.posts[p.ID] = stored
+
+	p.Votes = stored.Votes
+	p.UserID = stored.UserID
+	p.CreatedAt = stored.CreatedAt
+	p.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// DeletePost deletes a post with its comments
+func (s *Store) DeletePost(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.posts[id]; !ok {
+		return fmt.Errorf("Error deleting post: %w", goreddit.ErrNotFound)
+	}
+	s.deletePost(id)
+	return nil
+}
+
+// deletePost deletes a post with its comments and votes. The caller must
+// hold the lock.
+func (s *Store) deletePost(id uuid.UUID) {
+	delete(s.posts, id)
+	for v := range s.postVotes {
+		if v.targetID == id {
+			delete(s.postVotes, v)
+		}
+	}
+	for _, c := range s.comments {
+		if c.PostID == id {
+			s.deleteComment(c.ID)
+		}
+	}
+}
+
+// storedPost returns p without the fields that are joined in when reading
+func storedPost(p goreddit.Post) goreddit.Post {
+	p.CommentsCount = 0
+	p.ThreadTitle = ""
+	p.Author = ""
+	return p
+}
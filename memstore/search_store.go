@@ -0,0 +1,207 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// Weights of matches in titles, bodies and comments, the same as the A, B
+// and D weights of ts_rank
+const (
+	titleWeight   = 1.0
+	bodyWeight    = 0.4
+	commentWeight = 0.1
+)
+
+// snippetWords is the number of words of the body shown in a search result
+const snippetWords = 30
+
+// Search gets a page of the threads, posts and comments matching the query,
+// best matches first
+//
+// Unlike PostgreSQL full-text search there is no stemming: a word matches
+// the words of the query it starts with. Words of the query prefixed by a
+// minus must not appear.
+func (s *Store) Search(ctx context.Context, query string, threadID uuid.NullUUID, page goreddit.Page) ([]goreddit.SearchResult, goreddit.PageInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	q := parseQuery(query)
+	inThread := func(id uuid.UUID) bool { return !threadID.Valid || threadID.UUID == id }
+
+	var all []goreddit.SearchResult
+	var bodies []string
+	add := func(r goreddit.SearchResult, body string, rank float64) {
+		r.Rank = rank
+		all = append(all, r)
+		bodies = append(bodies, body)
+	}
+	for _, t := range s.threads {
+		if rank, ok := q.rank(titleWeight, t.Title, bodyWeight, t.Description); ok && inThread(t.ID) {
+			add(goreddit.SearchResult{Kind: "thread", ID: t.ID, ThreadID: t.ID, Title: t.Title}, t.Description, rank)
+		}
+	}
+	for _, p := range s.posts {
+		if rank, ok := q.rank(titleWeight, p.Title, bodyWeight, p.Content); ok && inThread(p.ThreadID) {
+			postID := uuid.NullUUID{UUID: p.ID, Valid: true}
+			add(goreddit.SearchResult{Kind: "post", ID: p.ID, ThreadID: p.ThreadID, PostID: postID, Title: p.Title}, p.Content, rank)
+		}
+	}
+	for _, c := range s.comments {
+		p := s.posts[c.PostID]
+		if rank, ok := q.rank(commentWeight, c.Content, 0, ""); ok && inThread(p.ThreadID) {
+			postID := uuid.NullUUID{UUID: p.ID, Valid: true}
+			add(goreddit.SearchResult{Kind: "comment", ID: c.ID, ThreadID: p.ThreadID, PostID: postID, Title: p.Title}, c.Content, rank)
+		}
+	}
+
+	keys := make([]goreddit.Cursor, len(all))
+	for i, r := range all {
+		keys[i] = goreddit.Cursor{Key: r.Rank, ID: r.ID}
+	}
+	idx, info, err := pageOf(page, keys)
+	if err != nil {
+		return []goreddit.SearchResult{}, goreddit.PageInfo{}, fmt.Errorf("Error searching: %w", err)
+	}
+
+	rr := make([]goreddit.SearchResult, 0, len(idx))
+	for _, i := range idx {
+		r := all[i]
+		r.Title = q.highlight(strings.Fields(r.Title))
+		r.Snippet = q.snippet(bodies[i])
+		rr = append(rr, r)
+	}
+	return rr, info, nil
+}
+
+// searchQuery holds the words that must and must not appear in a match
+type searchQuery struct {
+	include []string
+	exclude []string
+}
+
+// parseQuery splits a web search style query into lower case words
+func parseQuery(query string) searchQuery {
+	var q searchQuery
+	for _, field := range strings.Fields(query) {
+		exclude := strings.HasPrefix(field, "-")
+		for _, word := range words(field) {
+			if exclude {
+				q.exclude = append(q.exclude, word)
+			} else {
+				q.include = append(q.include, word)
+			}
+		}
+	}
+	return q
+}
+
+// words returns the lower case words of s
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// matches reports whether word matches one of the terms
+func matches(word string, terms []string) bool {
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// rank returns the weighted number of matching words in a title and body, and
+// whether they match the query at all
+func (q searchQuery) rank(titleWeight float64, title string, bodyWeight float64, body string) (float64, bool) {
+	if len(q.include) == 0 {
+		return 0, false
+	}
+
+	tw, bw := words(title), words(body)
+	all := append(append([]string{}, tw...), bw...)
+	for _, term := range q.include {
+		if !containsPrefix(all, term) {
+			return 0, false
+		}
+	}
+	for _, term := range q.exclude {
+		if containsPrefix(all, term) {
+			return 0, false
+		}
+	}
+
+	var rank float64
+	for _, w := range tw {
+		if matches(w, q.include) {
+			rank += titleWeight
+		}
+	}
+	for _, w := range bw {
+		if matches(w, q.include) {
+			rank += bodyWeight
+		}
+	}
+	return rank, true
+}
+
+// containsPrefix reports whether one of the words starts with term
+func containsPrefix(words []string, term string) bool {
+	for _, w := range words {
+		if strings.HasPrefix(w, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// snippet returns the part of body around the first match with the matching
+// words highlighted
+func (q searchQuery) snippet(body string) string {
+	fields := strings.Fields(body)
+	start := 0
+	for i, f := range fields {
+		if q.matchesField(f) {
+			start = i - snippetWords/3
+			break
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetWords
+	if end > len(fields) {
+		end = len(fields)
+	}
+	return q.highlight(fields[start:end])
+}
+
+// highlight joins the fields with spaces, marking the matching ones
+func (q searchQuery) highlight(fields []string) string {
+	marked := make([]string, len(fields))
+	for i, f := range fields {
+		if q.matchesField(f) {
+			f = goreddit.HighlightStart + f + goreddit.HighlightStop
+		}
+		marked[i] = f
+	}
+	return strings.Join(marked, " ")
+}
+
+// matchesField reports whether a word of the whitespace separated field
+// matches the query
+func (q searchQuery) matchesField(field string) bool {
+	for _, w := range words(field) {
+		if matches(w, q.include) {
+			return true
+		}
+	}
+	return false
+}
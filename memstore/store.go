@@ -0,0 +1,64 @@
+// Package memstore keeps the data of goreddit in memory, which is useful for
+// tests and local demos without PostgreSQL. Everything is lost when the
+// process exits.
+package memstore
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// NewStore initializes an empty Store pointer
+func NewStore() *Store {
+	return &Store{
+		threads:      map[uuid.UUID]goreddit.Thread{},
+		posts:        map[uuid.UUID]goreddit.Post{},
+		comments:     map[uuid.UUID]goreddit.Comment{},
+		users:        map[uuid.UUID]goreddit.User{},
+		postVotes:    map[vote]int{},
+		commentVotes: map[vote]int{},
+		moderators:   map[member]bool{},
+		bans:         map[member]bool{},
+	}
+}
+
+// Store implements goreddit.Store with maps that are safe for concurrent use
+//
+// The maps hold the stored columns only. Joined fields such as Author,
+// ThreadTitle and CommentsCount are filled in when reading, the same way the
+// postgres package does.
+type Store struct {
+	mu           sync.RWMutex
+	threads      map[uuid.UUID]goreddit.Thread
+	posts        map[uuid.UUID]goreddit.Post
+	comments     map[uuid.UUID]goreddit.Comment
+	users        map[uuid.UUID]goreddit.User
+	postVotes    map[vote]int
+	commentVotes map[vote]int
+	moderators   map[member]bool
+	bans         map[member]bool
+}
+
+// vote is the key of the vote of a user on a post or comment
+type vote struct {
+	userID   uuid.UUID
+	targetID uuid.UUID
+}
+
+// member is the key of a user listed for a thread, as a moderator or as a
+// banned user
+type member struct {
+	threadID uuid.UUID
+	userID   uuid.UUID
+}
+
+// author returns the username of the user with id, or an empty string if
+// there is none. The caller must hold the lock.
+func (s *Store) author(id uuid.NullUUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return s.users[id.UUID].Username
+}
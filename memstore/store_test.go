@@ -0,0 +1,14 @@
+package memstore
+
+import (
+	"testing"
+
+	"github.com/nahuakang/goreddit"
+	"github.com/nahuakang/goreddit/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) goreddit.Store {
+		return NewStore()
+	})
+}
@@ -0,0 +1,123 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// Thread gets a thread based on id input
+func (s *Store) Thread(ctx context.Context, id uuid.UUID) (goreddit.Thread, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.threads[id]
+	if !ok {
+		return goreddit.Thread{}, fmt.Errorf("Error getting thread: %w", goreddit.ErrNotFound)
+	}
+	t.Author = s.author(t.UserID)
+	return t, nil
+}
+
+// Threads gets a page of the threads, newest first
+func (s *Store) Threads(ctx context.Context, page goreddit.Page) ([]goreddit.Thread, goreddit.PageInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var all []goreddit.Thread
+	var keys []goreddit.Cursor
+	for _, t := range s.threads {
+		all = append(all, t)
+		keys = append(keys, goreddit.Cursor{Key: epoch(t.CreatedAt), ID: t.ID})
+	}
+
+	idx, info, err := pageOf(page, keys)
+	if err != nil {
+		return []goreddit.Thread{}, goreddit.PageInfo{}, fmt.Errorf("Error getting threads: %w", err)
+	}
+
+	tt := make([]goreddit.Thread, 0, len(idx))
+	for _, i := range idx {
+		t := all[i]
+		t.Author = s.author(t.UserID)
+		tt = append(tt, t)
+	}
+	return tt, info, nil
+}
+
+// CreateThread creates a thread
+func (s *Store) CreateThread(ctx context.Context, t *goreddit.Thread) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.threads[t.ID]; ok {
+		return fmt.Errorf("Error creating thread: %w", goreddit.ErrConflict)
+	}
+
+	t.CreatedAt = time.Now()
+	t.UpdatedAt = t.CreatedAt
+	stored := *t
+	stored.Author = ""
+	s.threads[t.ID] = stored
+	if t.UserID.Valid {
+		s.moderators[member{t.ID, t.UserID.UUID}] = true
+	}
+	return nil
+}
+
+// UpdateThread updates the title and description of a thread
+func (s *Store) UpdateThread(ctx context.Context, t *goreddit.Thread) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.threads[t.ID]
+	if !ok {
+		return fmt.Errorf("Error updating thread: %w", goreddit.ErrNotFound)
+	}
+
+	stored.Title = t.Title
+	stored.Description = t.Description
+	stored.UpdatedAt = time.Now()
+	s.threads[t.ID] = stored
+
+	t.UserID = stored.UserID
+	t.CreatedAt = stored.CreatedAt
+	t.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// DeleteThread deletes a thread with its posts and their comments
+func (s *Store) DeleteThread(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.threads[id]; !ok {
+		return fmt.Errorf("Error deleting thread: %w", goreddit.ErrNotFound)
+	}
+
+	delete(s.threads, id)
+	for _, p := range s.posts {
+		if p.ThreadID == id {
+			s.deletePost(p.ID)
+		}
+	}
+	for m := range s.moderators {
+		if m.threadID == id {
+			delete(s.moderators, m)
+		}
+	}
+	for m := range s.bans {
+		if m.threadID == id {
+			delete(s.bans, m)
+		}
+	}
+	return nil
+}
+
+// epoch returns t as seconds since the Unix epoch, like EXTRACT(EPOCH FROM t)
+func epoch(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
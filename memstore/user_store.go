@@ -0,0 +1,129 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// User gets a user based on id input
+func (s *Store) User(ctx context.Context, id uuid.UUID) (goreddit.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return goreddit.User{}, fmt.Errorf("Error getting user: %w", goreddit.ErrNotFound)
+	}
+	return u, nil
+}
+
+// UserByUsername gets a user based on username input
+func (s *Store) UserByUsername(ctx context.Context, username string) (goreddit.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return goreddit.User{}, fmt.Errorf("Error getting user: %w", goreddit.ErrNotFound)
+}
+
+// CreateUser creates a user
+func (s *Store) CreateUser(ctx context.Context, u *goreddit.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.usernameTaken(u.Username, uuid.Nil) {
+		return fmt.Errorf("Error creating user: %w", goreddit.ErrConflict)
+	}
+	if _, ok := s.users[u.ID]; ok {
+		return fmt.Errorf("Error creating user: %w", goreddit.ErrConflict)
+	}
+
+	s.users[u.ID] = *u
+	return nil
+}
+
+// UpdateUser updates a user
+func (s *Store) UpdateUser(ctx context.Context, u *goreddit.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[u.ID]; !ok {
+		return fmt.Errorf("Error updating user: %w", goreddit.ErrNotFound)
+	}
+	if s.usernameTaken(u.Username, u.ID) {
+		return fmt.Errorf("Error updating user: %w", goreddit.ErrConflict)
+	}
+
+	s.users[u.ID] = *u
+	return nil
+}
+
+// DeleteUser deletes a user with their votes, moderator roles and bans, keeping their threads, posts
+// and comments without an author
+func (s *Store) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return fmt.Errorf("Error deleting user: %w", goreddit.ErrNotFound)
+	}
+
+	delete(s.users, id)
+	for tid, t := range s.threads {
+		if t.UserID.Valid && t.UserID.UUID == id {
+			t.UserID = uuid.NullUUID{}
+			s.threads[tid] = t
+		}
+	}
+	for pid, p := range s.posts {
+		if p.UserID.Valid && p.UserID.UUID == id {
+			p.UserID = uuid.NullUUID{}
+			s.posts[pid] = p
+		}
+	}
+	for cid, c := range s.comments {
+		if c.UserID.Valid && c.UserID.UUID == id {
+			c.UserID = uuid.NullUUID{}
+			s.comments[cid] = c
+		}
+	}
+	for v := range s.postVotes {
+		if v.userID == id {
+			delete(s.postVotes, v)
+		}
+	}
+	for v := range s.commentVotes {
+		if v.userID == id {
+			delete(s.commentVotes, v)
+		}
+	}
+	for m := range s.moderators {
+		if m.userID == id {
+			delete(s.moderators, m)
+		}
+	}
+	for m := range s.bans {
+		if m.userID == id {
+			delete(s.bans, m)
+		}
+	}
+	return nil
+}
+
+// usernameTaken reports whether a user other than the user with id has the
+// username. The caller must hold the lock.
+func (s *Store) usernameTaken(username string, id uuid.UUID) bool {
+	for _, u := range s.users {
+		if u.Username == username && u.ID != id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// VotePost records the vote of a user on a post and adjusts the post score
+func (s *Store) VotePost(ctx context.Context, userID, postID uuid.UUID, value int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := checkVote(value); err != nil {
+		return fmt.Errorf("Error voting on post: %w", err)
+	}
+	p, ok := s.posts[postID]
+	if _, userOK := s.users[userID]; !ok || !userOK {
+		return fmt.Errorf("Error voting on post: %w", goreddit.ErrNotFound)
+	}
+
+	v := vote{userID: userID, targetID: postID}
+	p.Votes += value - s.postVotes[v]
+	s.posts[postID] = p
+	s.postVotes[v] = value
+	return nil
+}
+
+// VoteComment records the vote of a user on a comment and adjusts the comment score
+func (s *Store) VoteComment(ctx context.Context, userID, commentID uuid.UUID, value int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := checkVote(value); err != nil {
+		return fmt.Errorf("Error voting on comment: %w", err)
+	}
+	c, ok := s.comments[commentID]
+	if _, userOK := s.users[userID]; !ok || !userOK {
+		return fmt.Errorf("Error voting on comment: %w", goreddit.ErrNotFound)
+	}
+
+	v := vote{userID: userID, targetID: commentID}
+	c.Votes += value - s.commentVotes[v]
+	s.comments[commentID] = c
+	s.commentVotes[v] = value
+	return nil
+}
+
+// PostVotes gets the votes of a user on the given posts keyed by post id
+func (s *Store) PostVotes(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return votes(s.postVotes, userID, postIDs), nil
+}
+
+// CommentVotes gets the votes of a user on the given comments keyed by comment id
+func (s *Store) CommentVotes(ctx context.Context, userID uuid.UUID, commentIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return votes(s.commentVotes, userID, commentIDs), nil
+}
+
+// checkVote checks that value is a valid vote
+func checkVote(value int) error {
+	if value < -1 || value > 1 {
+		return fmt.Errorf("invalid vote value %d", value)
+	}
+	return nil
+}
+
+// votes gets the recorded votes of a user for the given target ids
+func votes(ledger map[vote]int, userID uuid.UUID, targetIDs []uuid.UUID) map[uuid.UUID]int {
+	vv := make(map[uuid.UUID]int, len(targetIDs))
+	for _, id := range targetIDs {
+		if value, ok := ledger[vote{userID: userID, targetID: id}]; ok {
+			vv[id] = value
+		}
+	}
+	return vv
+}
@@ -0,0 +1,5 @@
+DROP TRIGGER threads_moderator_insert ON threads;
+DROP FUNCTION add_thread_author_moderator;
+DROP TABLE thread_bans;
+DROP TABLE thread_moderators;
+ALTER TABLE users DROP COLUMN admin;
@@ -0,0 +1,28 @@
+ALTER TABLE users ADD COLUMN admin BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE thread_moderators (
+    thread_id UUID NOT NULL REFERENCES threads (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    PRIMARY KEY (thread_id, user_id)
+);
+
+CREATE TABLE thread_bans (
+    thread_id UUID NOT NULL REFERENCES threads (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    PRIMARY KEY (thread_id, user_id)
+);
+
+-- Authors moderate their threads, including the existing ones
+INSERT INTO thread_moderators (thread_id, user_id)
+    SELECT id, user_id FROM threads WHERE user_id IS NOT NULL;
+
+CREATE FUNCTION add_thread_author_moderator() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO thread_moderators (thread_id, user_id) VALUES (NEW.id, NEW.user_id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER threads_moderator_insert AFTER INSERT ON threads
+    FOR EACH ROW WHEN (NEW.user_id IS NOT NULL)
+    EXECUTE PROCEDURE add_thread_author_moderator();
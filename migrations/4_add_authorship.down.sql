@@ -0,0 +1,3 @@
+ALTER TABLE comments DROP COLUMN user_id;
+ALTER TABLE posts DROP COLUMN user_id;
+ALTER TABLE threads DROP COLUMN user_id;
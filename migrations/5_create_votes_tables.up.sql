@@ -0,0 +1,13 @@
+CREATE TABLE post_votes (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    value SMALLINT NOT NULL CHECK (value BETWEEN -1 AND 1),
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE TABLE comment_votes (
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    comment_id UUID NOT NULL REFERENCES comments (id) ON DELETE CASCADE,
+    value SMALLINT NOT NULL CHECK (value BETWEEN -1 AND 1),
+    PRIMARY KEY (user_id, comment_id)
+);
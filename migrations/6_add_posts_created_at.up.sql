@@ -0,0 +1,3 @@
+ALTER TABLE posts ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX posts_created_at_idx ON posts (created_at);
@@ -0,0 +1,8 @@
+ALTER TABLE threads ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE threads ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+ALTER TABLE posts ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+UPDATE posts SET updated_at = created_at;
+
+ALTER TABLE comments ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE comments ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
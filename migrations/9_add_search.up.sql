@@ -0,0 +1,17 @@
+ALTER TABLE threads ADD COLUMN search TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('english', description), 'B')
+) STORED;
+
+ALTER TABLE posts ADD COLUMN search TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('english', content), 'B')
+) STORED;
+
+ALTER TABLE comments ADD COLUMN search TSVECTOR GENERATED ALWAYS AS (
+    to_tsvector('english', content)
+) STORED;
+
+CREATE INDEX threads_search_idx ON threads USING GIN (search);
+CREATE INDEX posts_search_idx ON posts USING GIN (search);
+CREATE INDEX comments_search_idx ON comments USING GIN (search);
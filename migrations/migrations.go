@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL files that migrate the PostgreSQL schema,
+// named VERSION_NAME.up.sql and VERSION_NAME.down.sql
+package migrations
+
+import "embed"
+
+// FS contains the migration files
+//
+//go:embed *.sql
+var FS embed.FS
@@ -1,63 +1,242 @@
 package postgres
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 	"github.com/nahuakang/goreddit"
 )
 
-// CommentStore inherits sqlx.DB
+// CommentStore inherits DB
 type CommentStore struct {
-	*sqlx.DB
+	*DB
 }
 
+// commentColumns are the columns of the comments table mapped to goreddit.Comment
+const commentColumns = `comments.id, comments.post_id, comments.content, comments.votes,
+				comments.user_id, comments.created_at, comments.updated_at, comments.parent_id`
+
 // Comment retrieves a comment in the database
-func (s *CommentStore) Comment(id uuid.UUID) (goreddit.Comment, error) {
+func (s *CommentStore) Comment(ctx context.Context, id uuid.UUID) (goreddit.Comment, error) {
 	var c goreddit.Comment
-	if err := s.Get(&c, `SELECT * FROM comments WHERE id = $1`, id); err != nil {
-		return goreddit.Comment{}, fmt.Errorf("Error getting comment: %w", err)
+	if err := s.GetContext(ctx, &c, `SELECT `+commentColumns+` FROM comments WHERE id = $1`, id); err != nil {
+		return goreddit.Comment{}, fmt.Errorf("Error getting comment: %w", storeError(err))
 	}
 	return c, nil
 }
 
-// CommentsByPost retrives all comments of a post
-func (s *CommentStore) CommentsByPost(postID uuid.UUID) ([]goreddit.Comment, error) {
-	var cc []goreddit.Comment
-	if err := s.Select(&cc, `SELECT * FROM comments WHERE post_id = $1 ORDER BY votes DESC`, postID); err != nil {
-		return []goreddit.Comment{}, fmt.Errorf("Error getting comments: %w", err)
+// CommentsByPost retrives a page of the comments of a post
+func (s *CommentStore) CommentsByPost(ctx context.Context, postID uuid.UUID, page goreddit.Page) ([]goreddit.Comment, goreddit.PageInfo, error) {
+	k, err := newKeyset(page, "comments.votes", "comments.id", 2)
+	if err != nil {
+		return []goreddit.Comment{}, goreddit.PageInfo{}, fmt.Errorf("Error getting comments: %w", err)
+	}
+
+	var rows []commentRow
+	var query = `
+			SELECT
+				` + commentColumns + `,
+				COALESCE(users.username, '') AS author,
+				comments.votes::float8 AS sort_key
+			FROM comments
+			LEFT JOIN users ON users.id = comments.user_id
+			WHERE post_id = $1 AND ` + k.condition
+	if err := s.SelectContext(ctx, &rows, k.wrap(query), append([]interface{}{postID}, k.args...)...); err != nil {
+		return []goreddit.Comment{}, goreddit.PageInfo{}, fmt.Errorf("Error getting comments: %w", err)
+	}
+
+	keys := make([]goreddit.Cursor, len(rows))
+	for i, row := range rows {
+		keys[i] = goreddit.Cursor{Key: row.SortKey, ID: row.ID}
+	}
+
+	from, to, info := k.paginate(page, keys)
+	cc := make([]goreddit.Comment, 0, to-from)
+	for _, row := range rows[from:to] {
+		cc = append(cc, row.Comment)
+	}
+	return cc, info, nil
+}
+
+// CommentTree retrieves the comments of a post in display order, where every
+// comment is followed by its replies. Siblings are sorted by sort and only
+// comments up to maxDepth levels deep are returned. If rootID is valid the
+// tree starts at that comment instead of the top-level comments of the post.
+// The page selects the top-level comments of the tree.
+func (s *CommentStore) CommentTree(ctx context.Context, postID uuid.UUID, rootID uuid.NullUUID, sort goreddit.Sort, maxDepth int, page goreddit.Page) ([]goreddit.Comment, goreddit.PageInfo, error) {
+	k, err := newKeyset(page, "sort_key", "id", 4)
+	if err != nil {
+		return []goreddit.Comment{}, goreddit.PageInfo{}, fmt.Errorf("Error getting comments: %w", err)
+	}
+
+	var rows []commentRow
+	var query = commentTreeQuery(sort, k) + `
+			SELECT
+				id, post_id, parent_id, user_id, content, votes, author,
+				created_at, updated_at, depth, replies_count, sort_key
+			FROM tree
+			ORDER BY path`
+	if err := s.SelectContext(ctx, &rows, query, append([]interface{}{postID, rootID, maxDepth}, k.args...)...); err != nil {
+		return []goreddit.Comment{}, goreddit.PageInfo{}, fmt.Errorf("Error getting comments: %w", err)
+	}
+	cc, info := treePage(k, page, rows)
+	return cc, info, nil
+}
+
+// commentTreeQuery returns the WITH clause of the comment tree queries, whose
+// tree holds the comments in display order when sorted by path. The query
+// arguments are the post id, the root id, the maximum depth and then those
+// of the keyset.
+func commentTreeQuery(sort goreddit.Sort, k keyset) string {
+	return `
+			WITH RECURSIVE ranked AS (
+				SELECT
+					` + commentColumns + `,
+					COALESCE(users.username, '') AS author,
+					(SELECT COUNT(*) FROM comments AS replies WHERE replies.parent_id = comments.id) AS replies_count,
+					(` + commentSortKey(sort) + `)::float8 AS sort_key,
+					ROW_NUMBER() OVER (
+						PARTITION BY comments.parent_id
+						ORDER BY (` + commentSortKey(sort) + `)::float8 DESC, comments.id DESC
+					) AS rank
+				FROM comments
+				LEFT JOIN users ON users.id = comments.user_id
+				WHERE post_id = $1
+			), roots AS (
+				SELECT id
+				FROM ranked
+				WHERE (($2::uuid IS NULL AND parent_id IS NULL) OR id = $2) AND ` + k.condition + `
+				` + k.orderBy("sort_key", "id") + `
+			), tree AS (
+				SELECT ranked.*, 0 AS depth, ARRAY[ranked.rank] AS path
+				FROM ranked
+				WHERE ranked.id IN (SELECT id FROM roots)
+				UNION ALL
+				SELECT ranked.*, tree.depth + 1, tree.path || ranked.rank
+				FROM ranked
+				JOIN tree ON ranked.parent_id = tree.id
+				WHERE tree.depth + 1 < $3
+			)`
+}
+
+// treePage trims the rows fetched for a page of a comment tree, which is
+// paginated by the top-level comments, keeping the replies of each
+func treePage(k keyset, page goreddit.Page, rows []commentRow) ([]goreddit.Comment, goreddit.PageInfo) {
+	var roots []int
+	var keys []goreddit.Cursor
+	for i, row := range rows {
+		if row.Depth == 0 {
+			roots = append(roots, i)
+			keys = append(keys, goreddit.Cursor{Key: row.SortKey, ID: row.ID})
+		}
 	}
-	return cc, nil
+
+	from, to, info := k.paginate(page, keys)
+	start, end := len(rows), len(rows)
+	if from < len(roots) {
+		start = roots[from]
+	}
+	if to < len(roots) {
+		end = roots[to]
+	}
+
+	cc := make([]goreddit.Comment, 0, end-start)
+	for _, row := range rows[start:end] {
+		cc = append(cc, row.Comment)
+	}
+	return cc, info
+}
+
+// commentRow is a comment with the key it is sorted by in a listing
+type commentRow struct {
+	goreddit.Comment
+	SortKey float64 `db:"sort_key"`
+}
+
+// nullCommentRow is a commentRow whose columns may all be NULL, as in the
+// outer join of a post without comments to its comments
+type nullCommentRow struct {
+	ID           uuid.NullUUID   `db:"id"`
+	PostID       uuid.NullUUID   `db:"post_id"`
+	ParentID     uuid.NullUUID   `db:"parent_id"`
+	UserID       uuid.NullUUID   `db:"user_id"`
+	Content      sql.NullString  `db:"content"`
+	Votes        sql.NullInt64   `db:"votes"`
+	Author       sql.NullString  `db:"author"`
+	CreatedAt    sql.NullTime    `db:"created_at"`
+	UpdatedAt    sql.NullTime    `db:"updated_at"`
+	Depth        sql.NullInt64   `db:"depth"`
+	RepliesCount sql.NullInt64   `db:"replies_count"`
+	SortKey      sql.NullFloat64 `db:"sort_key"`
+}
+
+// commentRow returns the comment row, which is only meaningful if ID is valid
+func (r nullCommentRow) commentRow() commentRow {
+	return commentRow{
+		Comment: goreddit.Comment{
+			ID:           r.ID.UUID,
+			PostID:       r.PostID.UUID,
+			ParentID:     r.ParentID,
+			UserID:       r.UserID,
+			Content:      r.Content.String,
+			Votes:        int(r.Votes.Int64),
+			Author:       r.Author.String,
+			CreatedAt:    r.CreatedAt.Time,
+			UpdatedAt:    r.UpdatedAt.Time,
+			Depth:        int(r.Depth.Int64),
+			RepliesCount: int(r.RepliesCount.Int64),
+		},
+		SortKey: r.SortKey.Float64,
+	}
+}
+
+// commentSortKey returns the expression that sibling comments are sorted by
+// in descending order, with ties broken by the comment id
+func commentSortKey(sort goreddit.Sort) string {
+	if sort == goreddit.SortNew {
+		return "EXTRACT(EPOCH FROM comments.created_at)"
+	}
+	return "comments.votes"
 }
 
 // CreateComment creates a new comment
-func (s *CommentStore) CreateComment(c *goreddit.Comment) error {
-	if err := s.Get(c, `INSERT INTO comments VALUES ($1, $2, $3, $4) RETURNING *`,
+func (s *CommentStore) CreateComment(ctx context.Context, c *goreddit.Comment) error {
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	if err := s.GetContext(ctx, c, `INSERT INTO comments VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING `+commentColumns,
 		c.ID,
 		c.PostID,
 		c.Content,
-		c.Votes); err != nil {
-		return fmt.Errorf("Error creating comment: %w", err)
+		c.Votes,
+		c.UserID,
+		c.CreatedAt,
+		c.UpdatedAt,
+		c.ParentID); err != nil {
+		return fmt.Errorf("Error creating comment: %w", storeError(err))
 	}
 	return nil
 }
 
-// UpdateComment updates a comment
-func (s *CommentStore) UpdateComment(c *goreddit.Comment) error {
-	if err := s.Get(c, `UPDATE comments SET post_id = $1, content = $2, votes = $3 WHERE id = $4 RETURNING *`,
+// UpdateComment updates a comment. Its votes are left alone, as only votes
+// may change them.
+func (s *CommentStore) UpdateComment(ctx context.Context, c *goreddit.Comment) error {
+	c.UpdatedAt = time.Now()
+	if err := s.GetContext(ctx, c, `UPDATE comments SET post_id = $1, content = $2, updated_at = $3 WHERE id = $4 RETURNING `+commentColumns,
 		c.PostID,
 		c.Content,
-		c.Votes,
+		c.UpdatedAt,
 		c.ID); err != nil {
-		return fmt.Errorf("Error updating comment: %w", err)
+		return fmt.Errorf("Error updating comment: %w", storeError(err))
 	}
 	return nil
 }
 
 // DeleteComment deletes a comment
-func (s *CommentStore) DeleteComment(id uuid.UUID) error {
-	if _, err := s.Exec(`DELETE FROM comments WHERE id = $1`, id); err != nil {
+func (s *CommentStore) DeleteComment(ctx context.Context, id uuid.UUID) error {
+	if err := deleteError(s.ExecContext(ctx, `DELETE FROM comments WHERE id = $1`, id)); err != nil {
 		return fmt.Errorf("Error deleting comment: %w", err)
 	}
 	return nil
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/nahuakang/goreddit"
+)
+
+// PostgreSQL error codes of violated constraints
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+// storeError translates a database error into the matching error of the
+// goreddit package so that callers need not know about the database
+func storeError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return goreddit.ErrNotFound
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case uniqueViolation:
+			return goreddit.ErrConflict
+		case foreignKeyViolation:
+			// The thread, post or comment referred to does not exist
+			return goreddit.ErrNotFound
+		}
+	}
+	return err
+}
+
+// deleteError returns the error of a DELETE statement, which is
+// goreddit.ErrNotFound if it deleted no rows
+func deleteError(res sql.Result, err error) error {
+	if err != nil {
+		return storeError(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return goreddit.ErrNotFound
+	}
+	return nil
+}
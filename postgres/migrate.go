@@ -0,0 +1,254 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nahuakang/goreddit/migrations"
+)
+
+// migrationLock is the advisory lock held while migrating, so that two
+// migrators never change the schema at the same time
+const migrationLock = 7_001_113
+
+// versionQuery selects the schema version and whether it is dirty
+const versionQuery = `SELECT COALESCE(MAX(version), 0), COALESCE(BOOL_OR(dirty), false) FROM schema_migrations`
+
+// Migration is a change to the schema, with the SQL applying and reverting it
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrations returns the embedded migrations, sorted by version. Versions
+// must start at 1 and have no gaps.
+func Migrations() ([]Migration, error) {
+	return readMigrations(migrations.FS)
+}
+
+// readMigrations reads the VERSION_NAME.up.sql and VERSION_NAME.down.sql
+// files of fsys
+func readMigrations(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("Error reading migrations: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, f := range files {
+		name := f.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimSuffix(name, "."+direction+".sql"), "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || version < 1 || len(parts) != 2 {
+			return nil, fmt.Errorf("invalid migration name %q", name)
+		}
+
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading migrations: %w", err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = m
+		} else if m.Name != parts[1] {
+			return nil, fmt.Errorf("migration %d is named both %q and %q", version, m.Name, parts[1])
+		}
+		if direction == "up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	mm := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		mm = append(mm, *m)
+	}
+	sort.Slice(mm, func(i, j int) bool { return mm[i].Version < mm[j].Version })
+
+	for i, m := range mm {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+	}
+	return mm, nil
+}
+
+// Migrator brings the schema of a database to a version of the migrations.
+// The version is kept in the schema_migrations table, which is compatible
+// with the migrate CLI, so databases it migrated keep their version.
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+// NewMigrator connects to the database with the embedded migrations
+func NewMigrator(dataSourceName string) (*Migrator, error) {
+	mm, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sqlx.Open("postgres", dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Error connecting to database: %w", err)
+	}
+	return &Migrator{db: db, migrations: mm}, nil
+}
+
+// Close closes the database connections of the migrator
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Latest returns the version of the newest migration
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Migrations returns the migrations of the migrator, sorted by version
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Version returns the current schema version, which is 0 for an empty
+// database. Dirty is true if the migrate CLI failed halfway through a
+// migration, which must then be repaired by hand.
+func (m *Migrator) Version(ctx context.Context) (version int, dirty bool, err error) {
+	if err := m.createTable(ctx); err != nil {
+		return 0, false, err
+	}
+	if err := m.db.QueryRowContext(ctx, versionQuery).Scan(&version, &dirty); err != nil {
+		return 0, false, fmt.Errorf("Error getting schema version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// Up applies all the migrations that are newer than the current version
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the migration of the current version
+func (m *Migrator) Down(ctx context.Context) error {
+	version, _, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return errors.New("there is no migration to revert")
+	}
+	return m.To(ctx, version-1)
+}
+
+// To applies or reverts the migrations one by one until the schema is at the
+// version. Each migration runs in its own transaction, so a failing one
+// leaves the schema at the version before it.
+func (m *Migrator) To(ctx context.Context, version int) error {
+	if version < 0 || version > m.Latest() {
+		return fmt.Errorf("unknown version %d, want 0 to %d", version, m.Latest())
+	}
+	if err := m.createTable(ctx); err != nil {
+		return err
+	}
+
+	for {
+		done, err := m.step(ctx, version)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// step applies or reverts a single migration towards the version, returning
+// true once the schema is at the version
+func (m *Migrator) step(ctx context.Context, version int) (bool, error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("Error starting migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLock); err != nil {
+		return false, fmt.Errorf("Error locking migrations: %w", err)
+	}
+
+	// Read the version under the lock, as another migrator may have changed it
+	var current int
+	var dirty bool
+	if err := tx.QueryRowContext(ctx, versionQuery).Scan(&current, &dirty); err != nil {
+		return false, fmt.Errorf("Error getting schema version: %w", err)
+	}
+	if dirty {
+		return false, fmt.Errorf("schema version %d is dirty, repair the database and its schema_migrations table by hand", current)
+	}
+	if current > m.Latest() {
+		return false, fmt.Errorf("schema version %d is newer than the latest migration %d", current, m.Latest())
+	}
+	if current == version {
+		return true, nil
+	}
+
+	var query string
+	next := current + 1
+	if current < version {
+		query = m.migrations[current].Up
+	} else {
+		query = m.migrations[current-1].Down
+		next = current - 1
+	}
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return false, fmt.Errorf("Error migrating from version %d to %d: %w", current, next, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return false, fmt.Errorf("Error setting schema version: %w", err)
+	}
+	if next > 0 {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, next); err != nil {
+			return false, fmt.Errorf("Error setting schema version: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("Error committing migration: %w", err)
+	}
+	return next == version, nil
+}
+
+// createTable creates the schema_migrations table unless it exists
+func (m *Migrator) createTable(ctx context.Context) error {
+	if _, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY,
+		dirty BOOLEAN NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("Error creating schema_migrations: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigrations(t *testing.T) {
+	mm, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mm) == 0 {
+		t.Fatal("got no embedded migrations")
+	}
+	for i, m := range mm {
+		if m.Version != i+1 || m.Up == "" || m.Down == "" {
+			t.Errorf("migration %d: got version %d with up %t and down %t", i+1, m.Version, m.Up != "", m.Down != "")
+		}
+	}
+}
+
+func TestReadMigrationsErrors(t *testing.T) {
+	file := &fstest.MapFile{Data: []byte("SELECT 1;")}
+	for _, tt := range []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{
+			name:  "invalid name",
+			files: fstest.MapFS{"first.up.sql": file},
+			want:  `invalid migration name "first.up.sql"`,
+		},
+		{
+			name:  "missing version",
+			files: fstest.MapFS{"2_b.up.sql": file, "2_b.down.sql": file},
+			want:  "migration 1 is missing",
+		},
+		{
+			name:  "missing down file",
+			files: fstest.MapFS{"1_a.up.sql": file},
+			want:  "needs both an up and a down file",
+		},
+		{
+			name:  "different names",
+			files: fstest.MapFS{"1_a.up.sql": file, "1_b.down.sql": file},
+			want:  "is named both",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readMigrations(tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestMigrator reverts the last migration of the database named by
+// GOREDDIT_TEST_DSN and applies it again
+func TestMigrator(t *testing.T) {
+	dsn := os.Getenv("GOREDDIT_TEST_DSN")
+	if dsn == "" {
+		t.Skip("GOREDDIT_TEST_DSN is not set")
+	}
+
+	m, err := NewMigrator(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	ctx := context.Background()
+
+	checkVersion := func(want int) {
+		t.Helper()
+		version, dirty, err := m.Version(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != want || dirty {
+			t.Fatalf("got version %d, dirty %t; want version %d", version, dirty, want)
+		}
+	}
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	checkVersion(m.Latest())
+
+	if err := m.Down(ctx); err != nil {
+		t.Fatal(err)
+	}
+	checkVersion(m.Latest() - 1)
+
+	if err := m.To(ctx, m.Latest()); err != nil {
+		t.Fatal(err)
+	}
+	checkVersion(m.Latest())
+
+	if err := m.To(ctx, m.Latest()+1); err == nil {
+		t.Error("got no error migrating to an unknown version")
+	}
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/nahuakang/goreddit"
+)
+
+// keyset holds the parts of a listing query for keyset pagination
+type keyset struct {
+	// condition restricts the rows to those after (or before) the goreddit.Cursor
+	condition string
+	args      []interface{}
+	backward  bool
+	limit     int
+}
+
+// newKeyset builds the keyset condition of page for rows sorted by the key
+// and id SQL expressions. argN is the number of the first query argument used
+// by the condition.
+func newKeyset(page goreddit.Page, key, id string, argN int) (keyset, error) {
+	k := keyset{condition: "TRUE", limit: page.Limit}
+	if k.limit <= 0 {
+		k.limit = goreddit.DefaultPageLimit
+	}
+
+	s, op := page.After, "<"
+	if page.Before != "" {
+		s, op = page.Before, ">"
+		k.backward = true
+	}
+	if s == "" {
+		return k, nil
+	}
+
+	c, err := goreddit.DecodeCursor(s)
+	if err != nil {
+		return keyset{}, err
+	}
+	k.condition = fmt.Sprintf("((%s)::float8, %s) %s ($%d::float8, $%d::uuid)", key, id, op, argN, argN+1)
+	k.args = []interface{}{c.Key, c.ID}
+	return k, nil
+}
+
+// orderBy returns the ORDER BY and LIMIT clauses selecting the rows of the
+// page by the key and id SQL expressions. One extra row is fetched to find
+// out if there are more.
+func (k keyset) orderBy(key, id string) string {
+	dir := "DESC"
+	if k.backward {
+		dir = "ASC"
+	}
+	return fmt.Sprintf("ORDER BY %s %s, %s %s LIMIT %d", key, dir, id, dir, k.limit+1)
+}
+
+// wrap orders and limits query, which must select the key as sort_key and
+// the row id as id. The rows are always returned in descending order.
+func (k keyset) wrap(query string) string {
+	return `SELECT * FROM (` + query + `
+			` + k.orderBy("sort_key", "id") + `) AS page
+			ORDER BY sort_key DESC, id DESC`
+}
+
+// paginate returns the range of the fetched rows that belongs to the page,
+// dropping the extra row, and the cursors of the neighbouring pages. keys
+// holds the cursors of the fetched rows in order.
+func (k keyset) paginate(page goreddit.Page, keys []goreddit.Cursor) (from, to int, info goreddit.PageInfo) {
+	from, to = 0, len(keys)
+	more := len(keys) > k.limit
+	if more && k.backward {
+		from = to - k.limit
+	} else if more {
+		to = k.limit
+	}
+	if from == to {
+		return from, to, info
+	}
+
+	if (more && !k.backward) || (k.backward && page.Before != "") {
+		info.Next = keys[to-1].Encode()
+	}
+	if (more && k.backward) || (!k.backward && page.After != "") {
+		info.Prev = keys[from].Encode()
+	}
+	return from, to, info
+}
@@ -1,93 +1,264 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 	"github.com/nahuakang/goreddit"
 )
 
-// PostStore inherits from sqlx.DB
+// PostStore inherits from DB
 type PostStore struct {
-	*sqlx.DB
+	*DB
 }
 
+// postColumns are the columns of the posts table mapped to goreddit.Post
+const postColumns = `posts.id, posts.thread_id, posts.title, posts.content, posts.votes,
+				posts.user_id, posts.created_at, posts.updated_at`
+
 // Post method gets a post from the database based on id input
-func (s *PostStore) Post(id uuid.UUID) (goreddit.Post, error) {
+func (s *PostStore) Post(ctx context.Context, id uuid.UUID) (goreddit.Post, error) {
 	var p goreddit.Post
-	if err := s.Get(&p, `SELECT * FROM posts WHERE id = $1`, id); err != nil {
-		return goreddit.Post{}, fmt.Errorf("Error getting post: %w", err)
+	var query = `
+			SELECT
+				` + postColumns + `,
+				COALESCE(users.username, '') AS author
+			FROM posts
+			LEFT JOIN users ON users.id = posts.user_id
+			WHERE posts.id = $1`
+	if err := s.GetContext(ctx, &p, query, id); err != nil {
+		return goreddit.Post{}, fmt.Errorf("Error getting post: %w", storeError(err))
 	}
 	return p, nil
 }
 
-// PostsByThread gets all the posts from the database based on the thread id
-func (s *PostStore) PostsByThread(threadID uuid.UUID) ([]goreddit.Post, error) {
-	var pp []goreddit.Post
+// PostView gets a post with its thread and a page of its comment tree in a
+// single query, which returns a row for every comment, or a single row
+// without a comment if there are none
+func (s *PostStore) PostView(ctx context.Context, id uuid.UUID, rootID uuid.NullUUID, sort goreddit.Sort, maxDepth int, page goreddit.Page) (goreddit.PostView, error) {
+	k, err := newKeyset(page, "sort_key", "id", 4)
+	if err != nil {
+		return goreddit.PostView{}, fmt.Errorf("Error getting post: %w", err)
+	}
+
+	var rows []postViewRow
+	var query = commentTreeQuery(sort, k) + `
+			SELECT
+				posts.id AS "post.id", posts.thread_id AS "post.thread_id", posts.title AS "post.title",
+				posts.content AS "post.content", posts.votes AS "post.votes", posts.user_id AS "post.user_id",
+				posts.created_at AS "post.created_at", posts.updated_at AS "post.updated_at",
+				COALESCE(post_users.username, '') AS "post.author",
+				threads.id AS "thread.id", threads.title AS "thread.title", threads.description AS "thread.description",
+				threads.user_id AS "thread.user_id", threads.created_at AS "thread.created_at",
+				threads.updated_at AS "thread.updated_at",
+				COALESCE(thread_users.username, '') AS "thread.author",
+				tree.id AS "comment.id", tree.post_id AS "comment.post_id", tree.parent_id AS "comment.parent_id",
+				tree.user_id AS "comment.user_id", tree.content AS "comment.content", tree.votes AS "comment.votes",
+				tree.author AS "comment.author", tree.created_at AS "comment.created_at",
+				tree.updated_at AS "comment.updated_at", tree.depth AS "comment.depth",
+				tree.replies_count AS "comment.replies_count", tree.sort_key AS "comment.sort_key"
+			FROM posts
+			JOIN threads ON threads.id = posts.thread_id
+			LEFT JOIN users AS post_users ON post_users.id = posts.user_id
+			LEFT JOIN users AS thread_users ON thread_users.id = threads.user_id
+			LEFT JOIN tree ON TRUE
+			WHERE posts.id = $1
+			ORDER BY tree.path`
+	if err := s.SelectContext(ctx, &rows, query, append([]interface{}{id, rootID, maxDepth}, k.args...)...); err != nil {
+		return goreddit.PostView{}, fmt.Errorf("Error getting post: %w", err)
+	}
+	return postView(k, page, rows)
+}
+
+// postViewRow is a row of PostView: the post and its thread, and one of the
+// comments unless there are none
+type postViewRow struct {
+	Post    goreddit.Post   `db:"post"`
+	Thread  goreddit.Thread `db:"thread"`
+	Comment nullCommentRow  `db:"comment"`
+}
+
+// postView builds the PostView of the rows
+func postView(k keyset, page goreddit.Page, rows []postViewRow) (goreddit.PostView, error) {
+	if len(rows) == 0 {
+		return goreddit.PostView{}, fmt.Errorf("Error getting post: %w", goreddit.ErrNotFound)
+	}
+
+	var comments []commentRow
+	for _, row := range rows {
+		if row.Comment.ID.Valid {
+			comments = append(comments, row.Comment.commentRow())
+		}
+	}
+	cc, info := treePage(k, page, comments)
+	return goreddit.PostView{Post: rows[0].Post, Thread: rows[0].Thread, Comments: cc, PageInfo: info}, nil
+}
+
+// PostsByThread gets a page of the posts from the database based on the thread id
+func (s *PostStore) PostsByThread(ctx context.Context, threadID uuid.UUID, sort goreddit.PostSort, page goreddit.Page) ([]goreddit.Post, goreddit.PageInfo, error) {
+	k, err := newKeyset(page, postSortKey(sort), "posts.id", 2)
+	if err != nil {
+		return []goreddit.Post{}, goreddit.PageInfo{}, fmt.Errorf("Error getting posts: %w", err)
+	}
+
+	var rows []postRow
 	var query = `
 			SELECT
-				posts.*,
-				COUNT(comments.*) AS comments_count
+				` + postColumns + `,
+				COUNT(comments.*) AS comments_count,
+				COALESCE(users.username, '') AS author,
+				(` + postSortKey(sort) + `)::float8 AS sort_key
 			FROM posts
 			LEFT JOIN comments ON comments.post_id = posts.id
-			WHERE thread_id = $1
-			GROUP BY posts.id
-			ORDER BY votes DESC`
-	if err := s.Select(&pp, query, threadID); err != nil {
-		return []goreddit.Post{}, fmt.Errorf("Error getting posts: %w", err)
+			LEFT JOIN users ON users.id = posts.user_id
+			WHERE thread_id = $1 AND ` + postPeriodCondition(sort) + ` AND ` + k.condition + `
+			GROUP BY posts.id, users.username`
+	if err := s.SelectContext(ctx, &rows, k.wrap(query), append([]interface{}{threadID}, k.args...)...); err != nil {
+		return []goreddit.Post{}, goreddit.PageInfo{}, fmt.Errorf("Error getting posts: %w", err)
 	}
-	return pp, nil
+	pp, info := postPage(k, page, rows)
+	return pp, info, nil
 }
 
-// PostsByThread gets all the posts from the database based on the thread id
-func (s *PostStore) Posts() ([]goreddit.Post, error) {
-	var pp []goreddit.Post
+// Posts gets a page of the posts from the database
+func (s *PostStore) Posts(ctx context.Context, sort goreddit.PostSort, page goreddit.Page) ([]goreddit.Post, goreddit.PageInfo, error) {
+	k, err := newKeyset(page, postSortKey(sort), "posts.id", 1)
+	if err != nil {
+		return []goreddit.Post{}, goreddit.PageInfo{}, fmt.Errorf("Error getting posts: %w", err)
+	}
+
+	var rows []postRow
 	var query = `
 			SELECT
-							posts.*,
+							` + postColumns + `,
 							COUNT(comments.*) AS comments_count,
-							threads.title AS thread_title
+							threads.title AS thread_title,
+							COALESCE(users.username, '') AS author,
+							(` + postSortKey(sort) + `)::float8 AS sort_key
 			FROM posts
 			LEFT JOIN comments ON comments.post_id = posts.id
 			JOIN threads ON threads.id = posts.thread_id
-			GROUP BY posts.id, threads.title
-			ORDER BY votes DESC`
-	if err := s.Select(&pp, query); err != nil {
-		return []goreddit.Post{}, fmt.Errorf("Error getting posts: %w", err)
+			LEFT JOIN users ON users.id = posts.user_id
+			WHERE ` + postPeriodCondition(sort) + ` AND ` + k.condition + `
+			GROUP BY posts.id, threads.title, users.username`
+	if err := s.SelectContext(ctx, &rows, k.wrap(query), k.args...); err != nil {
+		return []goreddit.Post{}, goreddit.PageInfo{}, fmt.Errorf("Error getting posts: %w", err)
+	}
+	pp, info := postPage(k, page, rows)
+	return pp, info, nil
+}
+
+// postRow is a post with the key it is sorted by in a listing
+type postRow struct {
+	goreddit.Post
+	SortKey float64 `db:"sort_key"`
+}
+
+// postPage trims the rows fetched for a page of posts
+func postPage(k keyset, page goreddit.Page, rows []postRow) ([]goreddit.Post, goreddit.PageInfo) {
+	keys := make([]goreddit.Cursor, len(rows))
+	for i, row := range rows {
+		keys[i] = goreddit.Cursor{Key: row.SortKey, ID: row.ID}
+	}
+
+	from, to, info := k.paginate(page, keys)
+	pp := make([]goreddit.Post, 0, to-from)
+	for _, row := range rows[from:to] {
+		pp = append(pp, row.Post)
+	}
+	return pp, info
+}
+
+// postIntervals maps the listing periods to PostgreSQL intervals
+var postIntervals = map[goreddit.Period]string{
+	goreddit.PeriodHour:  "1 hour",
+	goreddit.PeriodDay:   "1 day",
+	goreddit.PeriodWeek:  "1 week",
+	goreddit.PeriodMonth: "1 month",
+	goreddit.PeriodYear:  "1 year",
+}
+
+// postPeriodCondition returns the WHERE condition restricting top and
+// controversial listings to the posts created within the sort period
+func postPeriodCondition(sort goreddit.PostSort) string {
+	if sort.Sort != goreddit.SortTop && sort.Sort != goreddit.SortControversial {
+		return "TRUE"
+	}
+	interval, ok := postIntervals[sort.Period]
+	if !ok {
+		return "TRUE"
+	}
+	return "posts.created_at > now() - interval '" + interval + "'"
+}
+
+// postSortKey returns the expression that post listings are sorted by in
+// descending order, with ties broken by the post id
+func postSortKey(sort goreddit.PostSort) string {
+	switch sort.Sort {
+	case goreddit.SortNew:
+		return "EXTRACT(EPOCH FROM posts.created_at)"
+	case goreddit.SortTop:
+		return "posts.votes"
+	case goreddit.SortControversial:
+		// Many votes that are evenly split rank highest
+		return `
+				SELECT CASE
+					WHEN ups = 0 OR downs = 0 THEN 0
+					ELSE (ups + downs) ^ (LEAST(ups, downs)::float / GREATEST(ups, downs))
+				END
+				FROM (
+					SELECT
+						COUNT(*) FILTER (WHERE value = 1) AS ups,
+						COUNT(*) FILTER (WHERE value = -1) AS downs
+					FROM post_votes
+					WHERE post_votes.post_id = posts.id
+				) AS counts`
+	default:
+		// Logarithmic score plus a bonus for newer posts, where every 12.5
+		// hours weigh as much as ten times the votes
+		return `SIGN(posts.votes) * LOG(GREATEST(ABS(posts.votes), 1)::float8)
+				+ EXTRACT(EPOCH FROM posts.created_at)::float8 / 45000`
 	}
-	return pp, nil
 }
 
 // CreatePost creates a post in the database
-func (s *PostStore) CreatePost(p *goreddit.Post) error {
-	if err := s.Get(p, `INSERT INTO posts VALUES ($1, $2, $3, $4, $5) RETURNING *`,
+func (s *PostStore) CreatePost(ctx context.Context, p *goreddit.Post) error {
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+	if err := s.GetContext(ctx, p, `INSERT INTO posts VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING `+postColumns,
 		p.ID,
 		p.ThreadID,
 		p.Title,
 		p.Content,
-		p.Votes); err != nil {
-		return fmt.Errorf("Error creating post: %w", err)
+		p.Votes,
+		p.UserID,
+		p.CreatedAt,
+		p.UpdatedAt); err != nil {
+		return fmt.Errorf("Error creating post: %w", storeError(err))
 	}
 	return nil
 }
 
-// UpdatePost updates a post in the database
-func (s *PostStore) UpdatePost(p *goreddit.Post) error {
-	if err := s.Get(p, `UPDATE posts SET thread_id = $1, title = $2, content = $3, votes = $4 WHERE id = $5 RETURNING *`,
+// UpdatePost updates a post in the database. Its votes are left alone, as
+// only votes may change them.
+func (s *PostStore) UpdatePost(ctx context.Context, p *goreddit.Post) error {
+	p.UpdatedAt = time.Now()
+	if err := s.GetContext(ctx, p, `UPDATE posts SET thread_id = $1, title = $2, content = $3, updated_at = $4 WHERE id = $5 RETURNING `+postColumns,
 		p.ThreadID,
 		p.Title,
 		p.Content,
-		p.Votes,
+		p.UpdatedAt,
 		p.ID); err != nil {
-		return fmt.Errorf("Error updating post: %w", err)
+		return fmt.Errorf("Error updating post: %w", storeError(err))
 	}
 	return nil
 }
 
 // DeletePost deletes a post in the database
-func (s *PostStore) DeletePost(id uuid.UUID) error {
-	if _, err := s.Exec(`DELETE FROM posts WHERE id = $1`, id); err != nil {
+func (s *PostStore) DeletePost(ctx context.Context, id uuid.UUID) error {
+	if err := deleteError(s.ExecContext(ctx, `DELETE FROM posts WHERE id = $1`, id)); err != nil {
 		return fmt.Errorf("Error deleting post: %w", err)
 	}
 	return nil
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// SearchStore inherits from DB
+type SearchStore struct {
+	*DB
+}
+
+// headlineOptions are the ts_headline options marking the matching words
+const headlineOptions = "StartSel=" + goreddit.HighlightStart +
+	", StopSel=" + goreddit.HighlightStop +
+	", MaxFragments=2, MaxWords=30, MinWords=10"
+
+// Search gets a page of the threads, posts and comments matching the query,
+// best matches first
+func (s *SearchStore) Search(ctx context.Context, query string, threadID uuid.NullUUID, page goreddit.Page) ([]goreddit.SearchResult, goreddit.PageInfo, error) {
+	k, err := newKeyset(page, "sort_key", "id", 4)
+	if err != nil {
+		return []goreddit.SearchResult{}, goreddit.PageInfo{}, fmt.Errorf("Error searching: %w", err)
+	}
+
+	// Only the results on the page get highlighted as ts_headline is slow
+	var matches = `
+			SELECT * FROM (
+				SELECT
+					'thread' AS kind,
+					threads.id,
+					threads.id AS thread_id,
+					NULL::uuid AS post_id,
+					threads.title,
+					threads.description AS body,
+					ts_rank(threads.search, q.query)::float8 AS sort_key
+				FROM threads
+				CROSS JOIN websearch_to_tsquery('english', $1) AS q(query)
+				WHERE threads.search @@ q.query AND ($2::uuid IS NULL OR threads.id = $2)
+				UNION ALL
+				SELECT
+					'post',
+					posts.id,
+					posts.thread_id,
+					posts.id,
+					posts.title,
+					posts.content,
+					ts_rank(posts.search, q.query)::float8
+				FROM posts
+				CROSS JOIN websearch_to_tsquery('english', $1) AS q(query)
+				WHERE posts.search @@ q.query AND ($2::uuid IS NULL OR posts.thread_id = $2)
+				UNION ALL
+				SELECT
+					'comment',
+					comments.id,
+					posts.thread_id,
+					comments.post_id,
+					posts.title,
+					comments.content,
+					ts_rank(comments.search, q.query)::float8
+				FROM comments
+				JOIN posts ON posts.id = comments.post_id
+				CROSS JOIN websearch_to_tsquery('english', $1) AS q(query)
+				WHERE comments.search @@ q.query AND ($2::uuid IS NULL OR posts.thread_id = $2)
+			) AS matches
+			WHERE ` + k.condition
+	var stmt = `
+			SELECT
+				kind,
+				id,
+				thread_id,
+				post_id,
+				ts_headline('english', title, websearch_to_tsquery('english', $1), $3) AS title,
+				ts_headline('english', body, websearch_to_tsquery('english', $1), $3) AS snippet,
+				sort_key AS rank
+			FROM (` + k.wrap(matches) + `) AS results
+			ORDER BY rank DESC, id DESC`
+
+	var rr []goreddit.SearchResult
+	if err := s.SelectContext(ctx, &rr, stmt, append([]interface{}{query, threadID, headlineOptions}, k.args...)...); err != nil {
+		return []goreddit.SearchResult{}, goreddit.PageInfo{}, fmt.Errorf("Error searching: %w", err)
+	}
+
+	keys := make([]goreddit.Cursor, len(rr))
+	for i, r := range rr {
+		keys[i] = goreddit.Cursor{Key: r.Rank, ID: r.ID}
+	}
+
+	from, to, info := k.paginate(page, keys)
+	return rr[from:to], info, nil
+}
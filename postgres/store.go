@@ -2,31 +2,43 @@ package postgres
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
 
-// NewStore initializes a Store pointer
-func NewStore(dataSourceName string) (*Store, error) {
-	db, err := sqlx.Open("postgres", dataSourceName)
+// NewStore initializes a Store pointer whose queries are cancelled after
+// queryTimeout, or only when their context is done if it is zero
+func NewStore(dataSourceName string, queryTimeout time.Duration) (*Store, error) {
+	sqlDB, err := sqlx.Open("postgres", dataSourceName)
 	if err != nil {
 		return nil, fmt.Errorf("Error opening database: %w", err)
 	}
-	if err := db.Ping(); err != nil {
+	if err := sqlDB.Ping(); err != nil {
 		return nil, fmt.Errorf("Error connecting to database: %w", err)
 	}
 
+	db := &DB{DB: sqlDB, timeout: queryTimeout}
+
 	return &Store{
-		ThreadStore:  &ThreadStore{DB: db},
-		PostStore:    &PostStore{DB: db},
-		CommentStore: &CommentStore{DB: db},
+		ThreadStore:    &ThreadStore{DB: db},
+		PostStore:      &PostStore{DB: db},
+		CommentStore:   &CommentStore{DB: db},
+		UserStore:      &UserStore{DB: db},
+		VoteStore:      &VoteStore{DB: db},
+		ModeratorStore: &ModeratorStore{DB: db},
+		SearchStore:    &SearchStore{DB: db},
 	}, nil
 }
 
-// Store contains the complete implementations of the 3 stores
+// Store contains the complete implementations of the 7 stores
 type Store struct {
 	*ThreadStore
 	*PostStore
 	*CommentStore
+	*UserStore
+	*VoteStore
+	*ModeratorStore
+	*SearchStore
 }
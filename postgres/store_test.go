@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"os"
+	"testing"
+
+	"github.com/nahuakang/goreddit"
+	"github.com/nahuakang/goreddit/storetest"
+)
+
+// newTestStore connects to the migrated database named by GOREDDIT_TEST_DSN,
+// skipping the test if it is not set
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	dsn := os.Getenv("GOREDDIT_TEST_DSN")
+	if dsn == "" {
+		t.Skip("GOREDDIT_TEST_DSN is not set")
+	}
+
+	s, err := NewStore(dsn, DefaultQueryTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// TestStore empties the database named by GOREDDIT_TEST_DSN before every test
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) goreddit.Store {
+		s := newTestStore(t)
+		if _, err := s.ThreadStore.Exec(`TRUNCATE users, threads CASCADE`); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// UserStore inherits from DB
+type UserStore struct {
+	*DB
+}
+
+// User gets a user from the database based on id input
+func (s *UserStore) User(ctx context.Context, id uuid.UUID) (goreddit.User, error) {
+	var u goreddit.User
+	if err := s.GetContext(ctx, &u, `SELECT * FROM users WHERE id = $1`, id); err != nil {
+		return goreddit.User{}, fmt.Errorf("Error getting user: %w", storeError(err))
+	}
+	return u, nil
+}
+
+// UserByUsername gets a user from the database based on username input
+func (s *UserStore) UserByUsername(ctx context.Context, username string) (goreddit.User, error) {
+	var u goreddit.User
+	if err := s.GetContext(ctx, &u, `SELECT * FROM users WHERE username = $1`, username); err != nil {
+		return goreddit.User{}, fmt.Errorf("Error getting user: %w", storeError(err))
+	}
+	return u, nil
+}
+
+// CreateUser creates a user in the database
+func (s *UserStore) CreateUser(ctx context.Context, u *goreddit.User) error {
+	if err := s.GetContext(ctx, u, `INSERT INTO users (id, username, password, admin) VALUES ($1, $2, $3, $4) RETURNING *`,
+		u.ID,
+		u.Username,
+		u.Password,
+		u.Admin); err != nil {
+		return fmt.Errorf("Error creating user: %w", storeError(err))
+	}
+	return nil
+}
+
+// UpdateUser updates a user in the database
+func (s *UserStore) UpdateUser(ctx context.Context, u *goreddit.User) error {
+	if err := s.GetContext(ctx, u, `UPDATE users SET username = $1, password = $2, admin = $3 WHERE id = $4 RETURNING *`,
+		u.Username,
+		u.Password,
+		u.Admin,
+		u.ID); err != nil {
+		return fmt.Errorf("Error updating user: %w", storeError(err))
+	}
+	return nil
+}
+
+// DeleteUser deletes a user in the database
+func (s *UserStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	if err := deleteError(s.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)); err != nil {
+		return fmt.Errorf("Error deleting user: %w", err)
+	}
+	return nil
+}
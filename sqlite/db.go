@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultQueryTimeout is the time after which queries are cancelled unless
+// NewStore is given another timeout
+const DefaultQueryTimeout = 5 * time.Second
+
+// DB inherits from sqlx.DB and cancels every query that takes longer than
+// the timeout, in addition to when the context of the query is done
+type DB struct {
+	*sqlx.DB
+	timeout time.Duration
+}
+
+// withTimeout returns a context that is cancelled after the query timeout.
+// A timeout of zero or less only uses the deadline of ctx, if any.
+func (db *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.timeout)
+}
+
+// GetContext runs a query that returns a single row within the query timeout
+func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	return db.DB.GetContext(ctx, dest, query, args...)
+}
+
+// SelectContext runs a query that returns rows within the query timeout
+func (db *DB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	return db.DB.SelectContext(ctx, dest, query, args...)
+}
+
+// ExecContext runs a query that returns no rows within the query timeout
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	return db.DB.ExecContext(ctx, query, args...)
+}
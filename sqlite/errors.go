@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/nahuakang/goreddit"
+)
+
+// storeError translates a database error into the matching error of the
+// goreddit package so that callers need not know about the database
+func storeError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return goreddit.ErrNotFound
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			return goreddit.ErrConflict
+		case sqlite3.ErrConstraintForeignKey:
+			// The thread, post or comment referred to does not exist
+			return goreddit.ErrNotFound
+		}
+	}
+	return err
+}
+
+// execError returns the error of an UPDATE or DELETE statement, which is
+// goreddit.ErrNotFound if it changed no rows
+func execError(res sql.Result, err error) error {
+	if err != nil {
+		return storeError(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return goreddit.ErrNotFound
+	}
+	return nil
+}
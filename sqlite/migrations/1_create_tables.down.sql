@@ -0,0 +1,6 @@
+DROP TABLE comment_votes;
+DROP TABLE post_votes;
+DROP TABLE comments;
+DROP TABLE posts;
+DROP TABLE threads;
+DROP TABLE users;
@@ -0,0 +1,55 @@
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE,
+    password TEXT NOT NULL
+);
+
+CREATE TABLE threads (
+    id TEXT PRIMARY KEY,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL,
+    user_id TEXT REFERENCES users (id) ON DELETE SET NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE posts (
+    id TEXT PRIMARY KEY,
+    thread_id TEXT NOT NULL REFERENCES threads (id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    votes INTEGER NOT NULL,
+    user_id TEXT REFERENCES users (id) ON DELETE SET NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX posts_thread_id_idx ON posts (thread_id);
+
+CREATE TABLE comments (
+    id TEXT PRIMARY KEY,
+    post_id TEXT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    parent_id TEXT REFERENCES comments (id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    votes INTEGER NOT NULL,
+    user_id TEXT REFERENCES users (id) ON DELETE SET NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX comments_post_id_idx ON comments (post_id);
+CREATE INDEX comments_parent_id_idx ON comments (parent_id);
+
+CREATE TABLE post_votes (
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    post_id TEXT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    value INTEGER NOT NULL CHECK (value BETWEEN -1 AND 1),
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE TABLE comment_votes (
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    comment_id TEXT NOT NULL REFERENCES comments (id) ON DELETE CASCADE,
+    value INTEGER NOT NULL CHECK (value BETWEEN -1 AND 1),
+    PRIMARY KEY (user_id, comment_id)
+);
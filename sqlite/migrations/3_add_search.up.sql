@@ -0,0 +1,43 @@
+-- Comments are indexed without a title, their results show the post title
+CREATE VIRTUAL TABLE search USING fts4(
+    kind, item_id, thread_id, post_id, title, body,
+    notindexed=kind, notindexed=item_id, notindexed=thread_id, notindexed=post_id,
+    tokenize=porter
+);
+
+CREATE TRIGGER threads_search_insert AFTER INSERT ON threads BEGIN
+    INSERT INTO search VALUES ('thread', new.id, new.id, NULL, new.title, new.description);
+END;
+CREATE TRIGGER threads_search_update AFTER UPDATE OF title, description ON threads BEGIN
+    UPDATE search SET title = new.title, body = new.description WHERE item_id = new.id;
+END;
+CREATE TRIGGER threads_search_delete AFTER DELETE ON threads BEGIN
+    DELETE FROM search WHERE item_id = old.id;
+END;
+
+CREATE TRIGGER posts_search_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO search VALUES ('post', new.id, new.thread_id, new.id, new.title, new.content);
+END;
+CREATE TRIGGER posts_search_update AFTER UPDATE OF thread_id, title, content ON posts BEGIN
+    UPDATE search SET thread_id = new.thread_id, title = new.title, body = new.content WHERE item_id = new.id;
+    UPDATE search SET thread_id = new.thread_id WHERE kind = 'comment' AND post_id = new.id;
+END;
+CREATE TRIGGER posts_search_delete AFTER DELETE ON posts BEGIN
+    DELETE FROM search WHERE item_id = old.id;
+END;
+
+CREATE TRIGGER comments_search_insert AFTER INSERT ON comments BEGIN
+    INSERT INTO search
+    SELECT 'comment', new.id, posts.thread_id, new.post_id, '', new.content
+    FROM posts WHERE posts.id = new.post_id;
+END;
+CREATE TRIGGER comments_search_update AFTER UPDATE OF post_id, content ON comments BEGIN
+    UPDATE search
+    SET post_id = new.post_id,
+        thread_id = (SELECT thread_id FROM posts WHERE posts.id = new.post_id),
+        body = new.content
+    WHERE item_id = new.id;
+END;
+CREATE TRIGGER comments_search_delete AFTER DELETE ON comments BEGIN
+    DELETE FROM search WHERE item_id = old.id;
+END;
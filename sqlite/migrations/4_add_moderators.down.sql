@@ -0,0 +1,5 @@
+DROP TRIGGER threads_moderator_insert;
+DROP TABLE thread_bans;
+DROP TABLE thread_moderators;
+-- DROP COLUMN needs SQLite 3.35 or later
+ALTER TABLE users DROP COLUMN admin;
@@ -0,0 +1,21 @@
+ALTER TABLE users ADD COLUMN admin INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE thread_moderators (
+    thread_id TEXT NOT NULL REFERENCES threads (id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    PRIMARY KEY (thread_id, user_id)
+);
+
+CREATE TABLE thread_bans (
+    thread_id TEXT NOT NULL REFERENCES threads (id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    PRIMARY KEY (thread_id, user_id)
+);
+
+-- Authors moderate their threads, including the existing ones
+INSERT INTO thread_moderators (thread_id, user_id)
+    SELECT id, user_id FROM threads WHERE user_id IS NOT NULL;
+
+CREATE TRIGGER threads_moderator_insert AFTER INSERT ON threads WHEN new.user_id IS NOT NULL BEGIN
+    INSERT INTO thread_moderators (thread_id, user_id) VALUES (new.id, new.user_id);
+END;
@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// ModeratorStore inherits from DB
+type ModeratorStore struct {
+	*DB
+}
+
+// Role gets the role of a user in a thread
+func (s *ModeratorStore) Role(ctx context.Context, userID, threadID uuid.UUID) (goreddit.Role, error) {
+	var r goreddit.Role
+	if err := s.GetContext(ctx, &r, `SELECT CASE
+			WHEN u.admin THEN 'admin'
+			WHEN EXISTS (SELECT 1 FROM thread_bans WHERE thread_id = ?2 AND user_id = u.id) THEN 'banned'
+			WHEN EXISTS (SELECT 1 FROM thread_moderators WHERE thread_id = ?2 AND user_id = u.id) THEN 'moderator'
+			ELSE 'user'
+		END FROM users u WHERE u.id = ?1`, userID, threadID); err != nil {
+		return goreddit.RoleGuest, fmt.Errorf("Error getting role: %w", storeError(err))
+	}
+	return r, nil
+}
+
+// Moderators gets the moderators of a thread ordered by username
+func (s *ModeratorStore) Moderators(ctx context.Context, threadID uuid.UUID) ([]goreddit.User, error) {
+	uu, err := s.members(ctx, "thread_moderators", threadID)
+	if err != nil {
+		return []goreddit.User{}, fmt.Errorf("Error getting moderators: %w", err)
+	}
+	return uu, nil
+}
+
+// AddModerator makes a user a moderator of a thread
+func (s *ModeratorStore) AddModerator(ctx context.Context, threadID, userID uuid.UUID) error {
+	if _, err := s.ExecContext(ctx, `INSERT INTO thread_moderators (thread_id, user_id) VALUES (?1, ?2)`,
+		threadID, userID); err != nil {
+		return fmt.Errorf("Error adding moderator: %w", storeError(err))
+	}
+	return nil
+}
+
+// RemoveModerator removes a user from the moderators of a thread
+func (s *ModeratorStore) RemoveModerator(ctx context.Context, threadID, userID uuid.UUID) error {
+	if err := execError(s.ExecContext(ctx, `DELETE FROM thread_moderators WHERE thread_id = ?1 AND user_id = ?2`,
+		threadID, userID)); err != nil {
+		return fmt.Errorf("Error removing moderator: %w", err)
+	}
+	return nil
+}
+
+// BannedUsers gets the users banned from a thread ordered by username
+func (s *ModeratorStore) BannedUsers(ctx context.Context, threadID uuid.UUID) ([]goreddit.User, error) {
+	uu, err := s.members(ctx, "thread_bans", threadID)
+	if err != nil {
+		return []goreddit.User{}, fmt.Errorf("Error getting banned users: %w", err)
+	}
+	return uu, nil
+}
+
+// BanUser bans a user from a thread
+func (s *ModeratorStore) BanUser(ctx context.Context, threadID, userID uuid.UUID) error {
+	if _, err := s.ExecContext(ctx, `INSERT INTO thread_bans (thread_id, user_id) VALUES (?1, ?2)`,
+		threadID, userID); err != nil {
+		return fmt.Errorf("Error banning user: %w", storeError(err))
+	}
+	return nil
+}
+
+// UnbanUser lifts the ban of a user from a thread
+func (s *ModeratorStore) UnbanUser(ctx context.Context, threadID, userID uuid.UUID) error {
+	if err := execError(s.ExecContext(ctx, `DELETE FROM thread_bans WHERE thread_id = ?1 AND user_id = ?2`,
+		threadID, userID)); err != nil {
+		return fmt.Errorf("Error unbanning user: %w", err)
+	}
+	return nil
+}
+
+// members gets the users listed for a thread in the table
+func (s *ModeratorStore) members(ctx context.Context, table string, threadID uuid.UUID) ([]goreddit.User, error) {
+	var uu []goreddit.User
+	if err := s.SelectContext(ctx, &uu, `SELECT u.* FROM users u
+			JOIN `+table+` m ON m.user_id = u.id
+			WHERE m.thread_id = ?1
+			ORDER BY u.username`, threadID); err != nil {
+		return nil, err
+	}
+	return uu, nil
+}
@@ -0,0 +1,132 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// SearchStore inherits from DB
+type SearchStore struct {
+	*DB
+}
+
+// Weights of matches in titles, bodies and comments, the same as the A, B
+// and D weights of ts_rank
+const (
+	titleWeight   = 1.0
+	bodyWeight    = 0.4
+	commentWeight = 0.1
+)
+
+// Columns of the search table as numbered by offsets()
+const (
+	searchTitleColumn = 4
+	searchBodyColumn  = 5
+)
+
+// Search gets a page of the threads, posts and comments matching the query,
+// best matches first
+//
+// The words of the query are stemmed by the porter tokenizer. Words
+// prefixed by a minus must not appear.
+func (s *SearchStore) Search(ctx context.Context, query string, threadID uuid.NullUUID, page goreddit.Page) ([]goreddit.SearchResult, goreddit.PageInfo, error) {
+	k, err := newKeyset(page, "sort_key", "id", 3)
+	if err != nil {
+		return []goreddit.SearchResult{}, goreddit.PageInfo{}, fmt.Errorf("Error searching: %w", err)
+	}
+
+	match := matchQuery(query)
+	if match == "" {
+		return []goreddit.SearchResult{}, goreddit.PageInfo{}, nil
+	}
+
+	// Comments are shown with the title of their post
+	var matches = `
+			SELECT * FROM (
+				SELECT
+					search.kind,
+					search.item_id AS id,
+					search.thread_id,
+					search.post_id,
+					CASE search.kind
+						WHEN 'comment' THEN posts.title
+						ELSE snippet(search, char(2), char(3), '', ` + strconv.Itoa(searchTitleColumn) + `, 64)
+					END AS title,
+					snippet(search, char(2), char(3), '…', ` + strconv.Itoa(searchBodyColumn) + `, 30) AS snippet,
+					search_rank(offsets(search), search.kind) AS sort_key
+				FROM search
+				LEFT JOIN posts ON posts.id = search.post_id
+				WHERE search MATCH ?1 AND (?2 IS NULL OR search.thread_id = ?2)
+			) AS matches
+			WHERE ` + k.condition
+	var stmt = `
+			SELECT kind, id, thread_id, post_id, title, snippet, sort_key AS rank
+			FROM (` + k.wrap(matches) + `) AS results
+			ORDER BY rank DESC, id DESC`
+
+	var rr []goreddit.SearchResult
+	if err := s.SelectContext(ctx, &rr, stmt, append([]interface{}{match, threadID}, k.args...)...); err != nil {
+		return []goreddit.SearchResult{}, goreddit.PageInfo{}, fmt.Errorf("Error searching: %w", err)
+	}
+
+	keys := make([]goreddit.Cursor, len(rr))
+	for i, r := range rr {
+		keys[i] = goreddit.Cursor{Key: r.Rank, ID: r.ID}
+	}
+
+	from, to, info := k.paginate(page, keys)
+	return rr[from:to], info, nil
+}
+
+// matchQuery turns a search query into an FTS query that requires all of
+// its words and excludes the words prefixed by a minus. Every word is
+// quoted so that users cannot write malformed FTS queries. The query is
+// empty if there are no required words, which FTS cannot search for.
+func matchQuery(query string) string {
+	var include, exclude []string
+	for _, word := range strings.Fields(query) {
+		negated := strings.HasPrefix(word, "-")
+		word = strings.Trim(word, `-"`)
+		word = strings.ReplaceAll(word, `"`, "")
+		if word == "" {
+			continue
+		}
+		if negated {
+			exclude = append(exclude, `"`+word+`"`)
+		} else {
+			include = append(include, `"`+word+`"`)
+		}
+	}
+	if len(include) == 0 {
+		return ""
+	}
+
+	q := strings.Join(include, " ")
+	for _, word := range exclude {
+		q += " NOT " + word
+	}
+	return q
+}
+
+// searchRank is the SQL function ranking a search match by the offsets() of
+// its matching words, weighted by the column they are in
+func searchRank(offsets, kind string) float64 {
+	fields := strings.Fields(offsets)
+	var rank float64
+	for i := 0; i+3 < len(fields); i += 4 {
+		switch column, _ := strconv.Atoi(fields[i]); {
+		case kind == "comment":
+			rank += commentWeight
+		case column == searchTitleColumn:
+			rank += titleWeight
+		case column == searchBodyColumn:
+			rank += bodyWeight
+		}
+	}
+	return rank
+}
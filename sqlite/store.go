@@ -0,0 +1,149 @@
+// Package sqlite implements goreddit.Store on a single SQLite database file,
+// which is handy to try goreddit without running PostgreSQL
+package sqlite
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// driverName is the name of the SQLite driver with the functions that
+// PostgreSQL has built in and the queries of this package use. Their
+// arguments must be REAL values.
+const driverName = "sqlite3_goreddit"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("log10", math.Log10, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("pow", math.Pow, true); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("search_rank", searchRank, true)
+		},
+	})
+}
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Open opens the SQLite database file at path, creating it if needed, and
+// brings its schema up to date
+func Open(path string) (*sqlx.DB, error) {
+	db, err := sqlx.Open(driverName, "file:"+path+"?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, fmt.Errorf("Error opening database: %w", err)
+	}
+	// SQLite allows only one writer at a time
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Error migrating database: %w", err)
+	}
+	return db, nil
+}
+
+// migrate applies the migrations that are newer than the schema version kept
+// in the user_version of the database
+func migrate(db *sqlx.DB) error {
+	var version int
+	if err := db.Get(&version, `PRAGMA user_version`); err != nil {
+		return err
+	}
+
+	files, err := migrations.ReadDir("migrations")
+	if err != nil {
+		return err
+	}
+	ups := map[int]string{}
+	var versions []int
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ".up.sql") {
+			continue
+		}
+		n, err := strconv.Atoi(strings.SplitN(f.Name(), "_", 2)[0])
+		if err != nil {
+			return fmt.Errorf("invalid migration name %q", f.Name())
+		}
+		ups[n] = f.Name()
+		versions = append(versions, n)
+	}
+	sort.Ints(versions)
+
+	for _, n := range versions {
+		if n <= version {
+			continue
+		}
+		if err := migrateTo(db, n, ups[n]); err != nil {
+			return fmt.Errorf("%s: %w", ups[n], err)
+		}
+	}
+	return nil
+}
+
+// migrateTo applies the migration in file and sets the schema version to n
+func migrateTo(db *sqlx.DB, n int, file string) error {
+	query, err := migrations.ReadFile(path.Join("migrations", file))
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(string(query)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, n)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// NewStore initializes a Store pointer on the SQLite database file at path.
+// Queries are cancelled after queryTimeout, or only when their context is
+// done if it is zero.
+func NewStore(path string, queryTimeout time.Duration) (*Store, error) {
+	sqlDB, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &DB{DB: sqlDB, timeout: queryTimeout}
+	return &Store{
+		ThreadStore:    &ThreadStore{DB: db},
+		PostStore:      &PostStore{DB: db},
+		CommentStore:   &CommentStore{DB: db},
+		UserStore:      &UserStore{DB: db},
+		VoteStore:      &VoteStore{DB: db},
+		ModeratorStore: &ModeratorStore{DB: db},
+		SearchStore:    &SearchStore{DB: db},
+	}, nil
+}
+
+// Store contains the complete implementations of the 7 stores
+type Store struct {
+	*ThreadStore
+	*PostStore
+	*CommentStore
+	*UserStore
+	*VoteStore
+	*ModeratorStore
+	*SearchStore
+}
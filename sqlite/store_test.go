@@ -0,0 +1,20 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nahuakang/goreddit"
+	"github.com/nahuakang/goreddit/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) goreddit.Store {
+		s, err := NewStore(filepath.Join(t.TempDir(), "goreddit.db"), DefaultQueryTimeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.ThreadStore.Close() })
+		return s
+	})
+}
@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// ThreadStore inherits methods from DB
+type ThreadStore struct {
+	*DB
+}
+
+// threadColumns are the columns of the threads table mapped to goreddit.Thread
+const threadColumns = `threads.id, threads.title, threads.description, threads.user_id,
+				threads.created_at, threads.updated_at`
+
+// Thread method gets a thread from the database based on id input
+func (s *ThreadStore) Thread(ctx context.Context, id uuid.UUID) (goreddit.Thread, error) {
+	var t goreddit.Thread
+	var query = `
+			SELECT
+				` + threadColumns + `,
+				COALESCE(users.username, '') AS author
+			FROM threads
+			LEFT JOIN users ON users.id = threads.user_id
+			WHERE threads.id = ?1`
+	if err := s.GetContext(ctx, &t, query, id); err != nil {
+		return goreddit.Thread{}, fmt.Errorf("Error getting thread: %w", storeError(err))
+	}
+	return t, nil
+}
+
+// Threads method gets a page of the threads in the database, newest first
+func (s *ThreadStore) Threads(ctx context.Context, page goreddit.Page) ([]goreddit.Thread, goreddit.PageInfo, error) {
+	k, err := newKeyset(page, threadSortKey, "threads.id", 1)
+	if err != nil {
+		return []goreddit.Thread{}, goreddit.PageInfo{}, fmt.Errorf("Error getting threads: %w", err)
+	}
+
+	var rows []threadRow
+	var query = `
+			SELECT
+				` + threadColumns + `,
+				COALESCE(users.username, '') AS author,
+				CAST((` + threadSortKey + `) AS REAL) AS sort_key
+			FROM threads
+			LEFT JOIN users ON users.id = threads.user_id
+			WHERE ` + k.condition
+	if err := s.SelectContext(ctx, &rows, k.wrap(query), k.args...); err != nil {
+		return []goreddit.Thread{}, goreddit.PageInfo{}, fmt.Errorf("Error getting threads: %w", err)
+	}
+
+	keys := make([]goreddit.Cursor, len(rows))
+	for i, row := range rows {
+		keys[i] = goreddit.Cursor{Key: row.SortKey, ID: row.ID}
+	}
+
+	from, to, info := k.paginate(page, keys)
+	tt := make([]goreddit.Thread, 0, to-from)
+	for _, row := range rows[from:to] {
+		tt = append(tt, row.Thread)
+	}
+	return tt, info, nil
+}
+
+// threadSortKey is the expression that thread listings are sorted by
+const threadSortKey = "(julianday(threads.created_at) - 2440587.5) * 86400"
+
+// threadRow is a thread with the key it is sorted by in a listing
+type threadRow struct {
+	goreddit.Thread
+	SortKey float64 `db:"sort_key"`
+}
+
+// CreateThread creates a thread in the database
+func (s *ThreadStore) CreateThread(ctx context.Context, t *goreddit.Thread) error {
+	t.CreatedAt = time.Now().UTC()
+	t.UpdatedAt = t.CreatedAt
+	if _, err := s.ExecContext(ctx, `INSERT INTO threads (id, title, description, user_id, created_at, updated_at)
+			VALUES (?1, ?2, ?3, ?4, ?5, ?6)`,
+		t.ID,
+		t.Title,
+		t.Description,
+		t.UserID,
+		t.CreatedAt,
+		t.UpdatedAt); err != nil {
+		return fmt.Errorf("Error creating thread: %w", storeError(err))
+	}
+	return nil
+}
+
+// UpdateThread updates a thread in the database
+func (s *ThreadStore) UpdateThread(ctx context.Context, t *goreddit.Thread) error {
+	t.UpdatedAt = time.Now().UTC()
+	if err := execError(s.ExecContext(ctx, `UPDATE threads SET title = ?1, description = ?2, updated_at = ?3 WHERE id = ?4`,
+		t.Title,
+		t.Description,
+		t.UpdatedAt,
+		t.ID)); err != nil {
+		return fmt.Errorf("Error updating thread: %w", err)
+	}
+	if err := s.GetContext(ctx, t, `SELECT `+threadColumns+` FROM threads WHERE id = ?1`, t.ID); err != nil {
+		return fmt.Errorf("Error updating thread: %w", storeError(err))
+	}
+	return nil
+}
+
+// DeleteThread deletes a thread in the database
+func (s *ThreadStore) DeleteThread(ctx context.Context, id uuid.UUID) error {
+	if err := execError(s.ExecContext(ctx, `DELETE FROM threads WHERE id = ?1`, id)); err != nil {
+		return fmt.Errorf("Error deleting thread: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// VoteStore inherits from DB
+type VoteStore struct {
+	*DB
+}
+
+// VotePost records the vote of a user on a post and adjusts the post score
+func (s *VoteStore) VotePost(ctx context.Context, userID, postID uuid.UUID, value int) error {
+	if err := s.vote(ctx, "post_votes", "post_id", "posts", userID, postID, value); err != nil {
+		return fmt.Errorf("Error voting on post: %w", err)
+	}
+	return nil
+}
+
+// VoteComment records the vote of a user on a comment and adjusts the comment score
+func (s *VoteStore) VoteComment(ctx context.Context, userID, commentID uuid.UUID, value int) error {
+	if err := s.vote(ctx, "comment_votes", "comment_id", "comments", userID, commentID, value); err != nil {
+		return fmt.Errorf("Error voting on comment: %w", err)
+	}
+	return nil
+}
+
+// PostVotes gets the votes of a user on the given posts keyed by post id
+func (s *VoteStore) PostVotes(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	vv, err := s.votes(ctx, "post_votes", "post_id", userID, postIDs)
+	if err != nil {
+		return map[uuid.UUID]int{}, fmt.Errorf("Error getting post votes: %w", err)
+	}
+	return vv, nil
+}
+
+// CommentVotes gets the votes of a user on the given comments keyed by comment id
+func (s *VoteStore) CommentVotes(ctx context.Context, userID uuid.UUID, commentIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	vv, err := s.votes(ctx, "comment_votes", "comment_id", userID, commentIDs)
+	if err != nil {
+		return map[uuid.UUID]int{}, fmt.Errorf("Error getting comment votes: %w", err)
+	}
+	return vv, nil
+}
+
+// vote upserts a row of the ledger table and applies the difference to the
+// cached votes of the target table in a single transaction. SQLite has a
+// single writer, so no rows need to be locked.
+func (s *VoteStore) vote(ctx context.Context, ledger, column, target string, userID, targetID uuid.UUID, value int) error {
+	if value < -1 || value > 1 {
+		return fmt.Errorf("invalid vote value %d", value)
+	}
+
+	// The timeout covers the whole transaction
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO `+ledger+` (user_id, `+column+`, value) VALUES (?1, ?2, 0)
+			ON CONFLICT DO NOTHING`, userID, targetID); err != nil {
+		return storeError(err)
+	}
+
+	var old int
+	if err := tx.GetContext(ctx, &old, `SELECT value FROM `+ledger+` WHERE user_id = ?1 AND `+column+` = ?2`,
+		userID, targetID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE `+ledger+` SET value = ?1 WHERE user_id = ?2 AND `+column+` = ?3`,
+		value, userID, targetID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE `+target+` SET votes = votes + ?1 WHERE id = ?2`,
+		value-old, targetID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// votes gets the ledger values of a user for the given target ids
+func (s *VoteStore) votes(ctx context.Context, ledger, column string, userID uuid.UUID, targetIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	if len(targetIDs) == 0 {
+		return map[uuid.UUID]int{}, nil
+	}
+
+	query, args, err := sqlx.In(`SELECT `+column+` AS target_id, value FROM `+ledger+`
+			WHERE user_id = ? AND `+column+` IN (?)`, userID, targetIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		TargetID uuid.UUID `db:"target_id"`
+		Value    int       `db:"value"`
+	}
+	if err := s.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	vv := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		vv[row.TargetID] = row.Value
+	}
+	return vv, nil
+}
@@ -0,0 +1,66 @@
+package storetest
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+func testCascadingDeletes(t *testing.T, s goreddit.Store) {
+	u := createUser(t, s, "alice")
+	th := createThread(t, s, "Go", uuid.NullUUID{})
+	kept := createThread(t, s, "Rust", uuid.NullUUID{})
+
+	p := createPost(t, s, th.ID, "Generics", 0)
+	c := createComment(t, s, p.ID, uuid.NullUUID{}, "Nice", 0)
+	reply := createComment(t, s, p.ID, valid(c.ID), "Agreed", 0)
+	keptPost := createPost(t, s, kept.ID, "Lifetimes", 0)
+	keptComment := createComment(t, s, keptPost.ID, uuid.NullUUID{}, "Hard", 0)
+	if err := s.VotePost(ctx, u.ID, p.ID, 1); err != nil {
+		t.Fatalf("VotePost: %v", err)
+	}
+	if err := s.VoteComment(ctx, u.ID, c.ID, 1); err != nil {
+		t.Fatalf("VoteComment: %v", err)
+	}
+
+	// Deleting a post deletes its comments and votes
+	if err := s.DeletePost(ctx, p.ID); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+	_, err := s.Comment(ctx, c.ID)
+	checkNotFound(t, "comment of deleted post", err)
+	_, err = s.Comment(ctx, reply.ID)
+	checkNotFound(t, "reply of deleted post", err)
+	vv, err := s.PostVotes(ctx, u.ID, []uuid.UUID{p.ID})
+	if err != nil || len(vv) != 0 {
+		t.Errorf("PostVotes of deleted post returned %v and error %v, want none", vv, err)
+	}
+	vv, err = s.CommentVotes(ctx, u.ID, []uuid.UUID{c.ID})
+	if err != nil || len(vv) != 0 {
+		t.Errorf("CommentVotes of deleted comment returned %v and error %v, want none", vv, err)
+	}
+
+	// Deleting a thread deletes its posts and their comments
+	p = createPost(t, s, th.ID, "Modules", 0)
+	c = createComment(t, s, p.ID, uuid.NullUUID{}, "Finally", 0)
+	if err := s.DeleteThread(ctx, th.ID); err != nil {
+		t.Fatalf("DeleteThread: %v", err)
+	}
+	_, err = s.Post(ctx, p.ID)
+	checkNotFound(t, "post of deleted thread", err)
+	_, err = s.Comment(ctx, c.ID)
+	checkNotFound(t, "comment of deleted thread", err)
+
+	pp, _, err := s.Posts(ctx, goreddit.PostSort{Sort: goreddit.SortNew}, goreddit.Page{})
+	if err != nil {
+		t.Fatalf("Posts: %v", err)
+	}
+	checkIDs(t, "Posts after deleting a thread", postIDs(pp), []uuid.UUID{keptPost.ID})
+	if len(pp) == 1 && pp[0].CommentsCount != 1 {
+		t.Errorf("Posts returned comments count %d for the post of the other thread, want 1", pp[0].CommentsCount)
+	}
+	if _, err := s.Comment(ctx, keptComment.ID); err != nil {
+		t.Errorf("Comment of the other thread: %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+func testComments(t *testing.T, s goreddit.Store) {
+	u := createUser(t, s, "alice")
+	th := createThread(t, s, "Go", uuid.NullUUID{})
+	p := createPost(t, s, th.ID, "Generics", 0)
+	other := createPost(t, s, th.ID, "Modules", 0)
+
+	c := goreddit.Comment{ID: uuid.New(), PostID: p.ID, UserID: valid(u.ID), Content: "Nice", Votes: 2}
+	if err := s.CreateComment(ctx, &c); err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+	if c.CreatedAt.IsZero() || !c.UpdatedAt.Equal(c.CreatedAt) {
+		t.Errorf("CreateComment set times %v, %v, want equal non-zero times", c.CreatedAt, c.UpdatedAt)
+	}
+
+	got, err := s.Comment(ctx, c.ID)
+	if err != nil {
+		t.Fatalf("Comment: %v", err)
+	}
+	if got.ID != c.ID || got.PostID != p.ID || got.ParentID.Valid || got.UserID != valid(u.ID) ||
+		got.Content != "Nice" || got.Votes != 2 {
+		t.Errorf("Comment returned %+v, want %+v", got, c)
+	}
+	if !sameTime(got.CreatedAt, c.CreatedAt) {
+		t.Errorf("Comment returned CreatedAt %v, want %v", got.CreatedAt, c.CreatedAt)
+	}
+
+	_, err = s.Comment(ctx, uuid.New())
+	checkNotFound(t, "Comment of unknown id", err)
+
+	orphan := goreddit.Comment{ID: uuid.New(), PostID: uuid.New(), Content: "Orphan"}
+	checkNotFound(t, "CreateComment on unknown post", s.CreateComment(ctx, &orphan))
+	orphan = goreddit.Comment{ID: uuid.New(), PostID: p.ID, ParentID: valid(uuid.New()), Content: "Orphan"}
+	checkNotFound(t, "CreateComment replying to unknown comment", s.CreateComment(ctx, &orphan))
+
+	dup := goreddit.Comment{ID: c.ID, PostID: p.ID, Content: "Duplicate"}
+	if err := s.CreateComment(ctx, &dup); !errors.Is(err, goreddit.ErrConflict) {
+		t.Errorf("CreateComment with existing id: got error %v, want ErrConflict", err)
+	}
+
+	reply := createComment(t, s, p.ID, valid(c.ID), "Agreed", 5)
+	best := createComment(t, s, p.ID, uuid.NullUUID{}, "Best", 10)
+	createComment(t, s, other.ID, uuid.NullUUID{}, "Elsewhere", 100)
+
+	// CommentsByPost lists all comments of the post by votes, replies included
+	cc, info, err := s.CommentsByPost(ctx, p.ID, goreddit.Page{})
+	if err != nil {
+		t.Fatalf("CommentsByPost: %v", err)
+	}
+	checkIDs(t, "CommentsByPost", commentIDs(cc), []uuid.UUID{best.ID, reply.ID, c.ID})
+	if info != (goreddit.PageInfo{}) {
+		t.Errorf("CommentsByPost on a single page returned cursors %+v", info)
+	}
+	if len(cc) == 3 && (cc[2].Author != "alice" || cc[1].ParentID != valid(c.ID)) {
+		t.Errorf("CommentsByPost returned author %q and parent %v, want %q and %v", cc[2].Author, cc[1].ParentID, "alice", c.ID)
+	}
+
+	got2 := walkPages(t, func(page goreddit.Page) ([]uuid.UUID, goreddit.PageInfo, error) {
+		cc, info, err := s.CommentsByPost(ctx, p.ID, page)
+		return commentIDs(cc), info, err
+	}, 2)
+	checkIDs(t, "CommentsByPost by pages of 2", got2, []uuid.UUID{best.ID, reply.ID, c.ID})
+
+	tick()
+	c.Content = "Very nice"
+	c.Votes = 20 // Only votes change the score
+	if err := s.UpdateComment(ctx, &c); err != nil {
+		t.Fatalf("UpdateComment: %v", err)
+	}
+	if !c.UpdatedAt.After(c.CreatedAt) {
+		t.Errorf("UpdateComment set UpdatedAt %v, want after CreatedAt %v", c.UpdatedAt, c.CreatedAt)
+	}
+	got, err = s.Comment(ctx, c.ID)
+	if err != nil {
+		t.Fatalf("Comment: %v", err)
+	}
+	if got.Content != "Very nice" || got.Votes != 2 || got.ParentID.Valid {
+		t.Errorf("Comment after update returned %q, %d, parent %v, want %q, 2, no parent", got.Content, got.Votes, got.ParentID, "Very nice")
+	}
+
+	missing := goreddit.Comment{ID: uuid.New(), PostID: p.ID, Content: "Missing"}
+	checkNotFound(t, "UpdateComment of unknown id", s.UpdateComment(ctx, &missing))
+
+	// Deleting a comment deletes its replies
+	if err := s.DeleteComment(ctx, c.ID); err != nil {
+		t.Fatalf("DeleteComment: %v", err)
+	}
+	_, err = s.Comment(ctx, c.ID)
+	checkNotFound(t, "Comment after delete", err)
+	_, err = s.Comment(ctx, reply.ID)
+	checkNotFound(t, "reply after deleting its parent", err)
+	checkNotFound(t, "DeleteComment of deleted comment", s.DeleteComment(ctx, c.ID))
+
+	cc, _, err = s.CommentsByPost(ctx, p.ID, goreddit.Page{})
+	if err != nil {
+		t.Fatalf("CommentsByPost: %v", err)
+	}
+	checkIDs(t, "CommentsByPost after delete", commentIDs(cc), []uuid.UUID{best.ID})
+}
+
+func testCommentTree(t *testing.T, s goreddit.Store) {
+	th := createThread(t, s, "Go", uuid.NullUUID{})
+	p := createPost(t, s, th.ID, "Generics", 0)
+
+	// a (1)            d (5)
+	// ├── b (3)        └── e (0)
+	// │   └── c (0)
+	// └── f (7)
+	a := createComment(t, s, p.ID, uuid.NullUUID{}, "a", 1)
+	tick()
+	b := createComment(t, s, p.ID, valid(a.ID), "b", 3)
+	tick()
+	c := createComment(t, s, p.ID, valid(b.ID), "c", 0)
+	tick()
+	d := createComment(t, s, p.ID, uuid.NullUUID{}, "d", 5)
+	tick()
+	e := createComment(t, s, p.ID, valid(d.ID), "e", 0)
+	tick()
+	f := createComment(t, s, p.ID, valid(a.ID), "f", 7)
+
+	type node struct {
+		id      uuid.UUID
+		depth   int
+		replies int
+	}
+	tests := []struct {
+		name     string
+		root     uuid.NullUUID
+		sort     goreddit.Sort
+		maxDepth int
+		want     []node
+	}{
+		{"top", uuid.NullUUID{}, goreddit.SortTop, 10, []node{
+			{d.ID, 0, 1}, {e.ID, 1, 0}, {a.ID, 0, 2}, {f.ID, 1, 0}, {b.ID, 1, 1}, {c.ID, 2, 0},
+		}},
+		{"new", uuid.NullUUID{}, goreddit.SortNew, 10, []node{
+			{d.ID, 0, 1}, {e.ID, 1, 0}, {a.ID, 0, 2}, {f.ID, 1, 0}, {b.ID, 1, 1}, {c.ID, 2, 0},
+		}},
+		{"max depth", uuid.NullUUID{}, goreddit.SortTop, 2, []node{
+			{d.ID, 0, 1}, {e.ID, 1, 0}, {a.ID, 0, 2}, {f.ID, 1, 0}, {b.ID, 1, 1},
+		}},
+		{"top level only", uuid.NullUUID{}, goreddit.SortTop, 1, []node{
+			{d.ID, 0, 1}, {a.ID, 0, 2},
+		}},
+		{"from reply", valid(b.ID), goreddit.SortTop, 10, []node{
+			{b.ID, 0, 1}, {c.ID, 1, 0},
+		}},
+	}
+	for _, tt := range tests {
+		cc, info, err := s.CommentTree(ctx, p.ID, tt.root, tt.sort, tt.maxDepth, goreddit.Page{})
+		if err != nil {
+			t.Fatalf("CommentTree %s: %v", tt.name, err)
+		}
+		if info != (goreddit.PageInfo{}) {
+			t.Errorf("CommentTree %s on a single page returned cursors %+v", tt.name, info)
+		}
+		got := make([]node, len(cc))
+		for i, c := range cc {
+			got[i] = node{c.ID, c.Depth, c.RepliesCount}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("CommentTree %s returned %d comments, want %d", tt.name, len(got), len(tt.want))
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("CommentTree %s returned %+v at %d, want %+v", tt.name, got[i], i, tt.want[i])
+			}
+		}
+	}
+
+	// The new order puts f before b since it is newer
+	cc, _, err := s.CommentTree(ctx, p.ID, valid(a.ID), goreddit.SortNew, 10, goreddit.Page{})
+	if err != nil {
+		t.Fatalf("CommentTree: %v", err)
+	}
+	checkIDs(t, "CommentTree sorted by new", commentIDs(cc), []uuid.UUID{a.ID, f.ID, b.ID, c.ID})
+
+	cc, _, err = s.CommentTree(ctx, p.ID, valid(uuid.New()), goreddit.SortTop, 10, goreddit.Page{})
+	if err != nil || len(cc) != 0 {
+		t.Errorf("CommentTree from unknown comment returned %d comments and error %v, want none", len(cc), err)
+	}
+
+	// Pages hold top-level comments with all of their replies
+	cc, info, err := s.CommentTree(ctx, p.ID, uuid.NullUUID{}, goreddit.SortTop, 10, goreddit.Page{Limit: 1})
+	if err != nil {
+		t.Fatalf("CommentTree: %v", err)
+	}
+	checkIDs(t, "CommentTree first page", commentIDs(cc), []uuid.UUID{d.ID, e.ID})
+	if info.Next == "" || info.Prev != "" {
+		t.Fatalf("CommentTree first page returned cursors %+v, want only next", info)
+	}
+	cc, info, err = s.CommentTree(ctx, p.ID, uuid.NullUUID{}, goreddit.SortTop, 10, goreddit.Page{After: info.Next, Limit: 1})
+	if err != nil {
+		t.Fatalf("CommentTree: %v", err)
+	}
+	checkIDs(t, "CommentTree second page", commentIDs(cc), []uuid.UUID{a.ID, f.ID, b.ID, c.ID})
+	if info.Next != "" || info.Prev == "" {
+		t.Fatalf("CommentTree second page returned cursors %+v, want only prev", info)
+	}
+	cc, _, err = s.CommentTree(ctx, p.ID, uuid.NullUUID{}, goreddit.SortTop, 10, goreddit.Page{Before: info.Prev, Limit: 1})
+	if err != nil {
+		t.Fatalf("CommentTree: %v", err)
+	}
+	checkIDs(t, "CommentTree back to first page", commentIDs(cc), []uuid.UUID{d.ID, e.ID})
+}
@@ -0,0 +1,128 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+func testModerators(t *testing.T, s goreddit.Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	carol := createUser(t, s, "carol")
+	th := createThread(t, s, "Go", valid(alice.ID))
+	other := createThread(t, s, "Rust", uuid.NullUUID{})
+
+	checkRole := func(what string, userID, threadID uuid.UUID, want goreddit.Role) {
+		t.Helper()
+		r, err := s.Role(ctx, userID, threadID)
+		if err != nil {
+			t.Fatalf("Role %s: %v", what, err)
+		}
+		if r != want {
+			t.Errorf("Role %s: got %q, want %q", what, r, want)
+		}
+	}
+	checkUsers := func(what string, uu []goreddit.User, err error, want ...goreddit.User) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", what, err)
+		}
+		got := make([]uuid.UUID, len(uu))
+		for i, u := range uu {
+			got[i] = u.ID
+		}
+		ids := make([]uuid.UUID, len(want))
+		for i, u := range want {
+			ids[i] = u.ID
+		}
+		checkIDs(t, what, got, ids)
+	}
+
+	// The author moderates the thread from its creation
+	checkRole("of author", alice.ID, th.ID, goreddit.RoleModerator)
+	checkRole("of other user", bob.ID, th.ID, goreddit.RoleUser)
+	checkRole("of author in other thread", alice.ID, other.ID, goreddit.RoleUser)
+	checkRole("outside of threads", alice.ID, uuid.Nil, goreddit.RoleUser)
+	_, err := s.Role(ctx, uuid.New(), th.ID)
+	checkNotFound(t, "Role of unknown user", err)
+
+	if err := s.AddModerator(ctx, th.ID, carol.ID); err != nil {
+		t.Fatalf("AddModerator: %v", err)
+	}
+	checkRole("of added moderator", carol.ID, th.ID, goreddit.RoleModerator)
+	uu, err := s.Moderators(ctx, th.ID)
+	checkUsers("Moderators", uu, err, alice, carol)
+	uu, err = s.Moderators(ctx, other.ID)
+	checkUsers("Moderators of thread without moderators", uu, err)
+
+	if err := s.AddModerator(ctx, th.ID, carol.ID); !errors.Is(err, goreddit.ErrConflict) {
+		t.Errorf("AddModerator twice: got error %v, want ErrConflict", err)
+	}
+	checkNotFound(t, "AddModerator to unknown thread", s.AddModerator(ctx, uuid.New(), bob.ID))
+	checkNotFound(t, "AddModerator of unknown user", s.AddModerator(ctx, th.ID, uuid.New()))
+
+	if err := s.RemoveModerator(ctx, th.ID, carol.ID); err != nil {
+		t.Fatalf("RemoveModerator: %v", err)
+	}
+	checkRole("of removed moderator", carol.ID, th.ID, goreddit.RoleUser)
+	checkNotFound(t, "RemoveModerator of removed moderator", s.RemoveModerator(ctx, th.ID, carol.ID))
+
+	// Bans apply to one thread and outrank moderation
+	if err := s.BanUser(ctx, th.ID, bob.ID); err != nil {
+		t.Fatalf("BanUser: %v", err)
+	}
+	if err := s.BanUser(ctx, th.ID, alice.ID); err != nil {
+		t.Fatalf("BanUser of moderator: %v", err)
+	}
+	checkRole("of banned user", bob.ID, th.ID, goreddit.RoleBanned)
+	checkRole("of banned moderator", alice.ID, th.ID, goreddit.RoleBanned)
+	checkRole("of banned user in other thread", bob.ID, other.ID, goreddit.RoleUser)
+	uu, err = s.BannedUsers(ctx, th.ID)
+	checkUsers("BannedUsers", uu, err, alice, bob)
+
+	if err := s.BanUser(ctx, th.ID, bob.ID); !errors.Is(err, goreddit.ErrConflict) {
+		t.Errorf("BanUser twice: got error %v, want ErrConflict", err)
+	}
+	checkNotFound(t, "BanUser from unknown thread", s.BanUser(ctx, uuid.New(), bob.ID))
+
+	if err := s.UnbanUser(ctx, th.ID, alice.ID); err != nil {
+		t.Fatalf("UnbanUser: %v", err)
+	}
+	checkRole("of unbanned moderator", alice.ID, th.ID, goreddit.RoleModerator)
+	checkNotFound(t, "UnbanUser of unbanned user", s.UnbanUser(ctx, th.ID, alice.ID))
+
+	// Admins may do anything even when banned, and their flag is stored
+	carol.Admin = true
+	if err := s.UpdateUser(ctx, &carol); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	got, err := s.User(ctx, carol.ID)
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	if !got.Admin {
+		t.Errorf("User returned %+v, want an admin", got)
+	}
+	if err := s.BanUser(ctx, th.ID, carol.ID); err != nil {
+		t.Fatalf("BanUser of admin: %v", err)
+	}
+	checkRole("of banned admin", carol.ID, th.ID, goreddit.RoleAdmin)
+	checkRole("of admin outside of threads", carol.ID, uuid.Nil, goreddit.RoleAdmin)
+
+	// Moderators and bans go with their thread and their user
+	if err := s.DeleteUser(ctx, bob.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	uu, err = s.BannedUsers(ctx, th.ID)
+	checkUsers("BannedUsers after DeleteUser", uu, err, carol)
+	if err := s.DeleteThread(ctx, th.ID); err != nil {
+		t.Fatalf("DeleteThread: %v", err)
+	}
+	uu, err = s.Moderators(ctx, th.ID)
+	checkUsers("Moderators after DeleteThread", uu, err)
+	uu, err = s.BannedUsers(ctx, th.ID)
+	checkUsers("BannedUsers after DeleteThread", uu, err)
+}
@@ -0,0 +1,107 @@
+package storetest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// lister gets a page of a listing as the ids of its items
+type lister func(page goreddit.Page) ([]uuid.UUID, goreddit.PageInfo, error)
+
+// walkPages gets all pages of a listing with limit items per page, following
+// the Next cursors from the first page and then the Prev cursors back from
+// the last one. It checks that both ways return the same pages and returns
+// the ids of all items in order.
+func walkPages(t *testing.T, list lister, limit int) []uuid.UUID {
+	t.Helper()
+
+	var pages [][]uuid.UUID
+	var info goreddit.PageInfo
+	page := goreddit.Page{Limit: limit}
+	for {
+		ids, next, err := list(page)
+		if err != nil {
+			t.Fatalf("getting page %d: %v", len(pages)+1, err)
+		}
+		if len(pages) == 0 && next.Prev != "" {
+			t.Errorf("first page has a previous page")
+		}
+		if len(ids) > limit {
+			t.Fatalf("page %d has %d items, want at most %d", len(pages)+1, len(ids), limit)
+		}
+		pages = append(pages, ids)
+		info = next
+		if next.Next == "" {
+			break
+		}
+		if len(pages) > 100 {
+			t.Fatalf("more than 100 pages, the Next cursors are going in circles")
+		}
+		page = goreddit.Page{After: next.Next, Limit: limit}
+	}
+
+	prev := info.Prev
+	for i := len(pages) - 2; i >= 0; i-- {
+		if prev == "" {
+			t.Fatalf("page %d has no previous page", i+2)
+		}
+		ids, back, err := list(goreddit.Page{Before: prev, Limit: limit})
+		if err != nil {
+			t.Fatalf("getting page %d backwards: %v", i+1, err)
+		}
+		if !reflect.DeepEqual(ids, pages[i]) {
+			t.Errorf("page %d backwards is %v, want %v", i+1, ids, pages[i])
+		}
+		prev = back.Prev
+	}
+	if len(pages) > 1 && prev != "" {
+		t.Errorf("first page reached backwards has a previous page")
+	}
+
+	var all []uuid.UUID
+	for _, ids := range pages {
+		all = append(all, ids...)
+	}
+	return all
+}
+
+// threadIDs returns the ids of the threads
+func threadIDs(tt []goreddit.Thread) []uuid.UUID {
+	ids := make([]uuid.UUID, len(tt))
+	for i, th := range tt {
+		ids[i] = th.ID
+	}
+	return ids
+}
+
+// postIDs returns the ids of the posts
+func postIDs(pp []goreddit.Post) []uuid.UUID {
+	ids := make([]uuid.UUID, len(pp))
+	for i, p := range pp {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+// commentIDs returns the ids of the comments
+func commentIDs(cc []goreddit.Comment) []uuid.UUID {
+	ids := make([]uuid.UUID, len(cc))
+	for i, c := range cc {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+// checkIDs fails the test unless the ids are the wanted ones in order
+func checkIDs(t *testing.T, what string, got, want []uuid.UUID) {
+	t.Helper()
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s:\ngot  %v\nwant %v", what, got, want)
+	}
+}
@@ -0,0 +1,227 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+func testPosts(t *testing.T, s goreddit.Store) {
+	u := createUser(t, s, "alice")
+	th := createThread(t, s, "Go", uuid.NullUUID{})
+	other := createThread(t, s, "Rust", uuid.NullUUID{})
+
+	p := goreddit.Post{ID: uuid.New(), ThreadID: th.ID, UserID: valid(u.ID), Title: "Generics", Content: "Finally", Votes: 3}
+	if err := s.CreatePost(ctx, &p); err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	if p.CreatedAt.IsZero() || !p.UpdatedAt.Equal(p.CreatedAt) {
+		t.Errorf("CreatePost set times %v, %v, want equal non-zero times", p.CreatedAt, p.UpdatedAt)
+	}
+	otherPost := createPost(t, s, other.ID, "Lifetimes", 0)
+
+	got, err := s.Post(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if got.ID != p.ID || got.ThreadID != th.ID || got.UserID != valid(u.ID) ||
+		got.Title != "Generics" || got.Content != "Finally" || got.Votes != 3 {
+		t.Errorf("Post returned %+v, want %+v", got, p)
+	}
+	if got.Author != "alice" {
+		t.Errorf("Post returned author %q, want %q", got.Author, "alice")
+	}
+	if !sameTime(got.CreatedAt, p.CreatedAt) {
+		t.Errorf("Post returned CreatedAt %v, want %v", got.CreatedAt, p.CreatedAt)
+	}
+
+	_, err = s.Post(ctx, uuid.New())
+	checkNotFound(t, "Post of unknown id", err)
+
+	orphan := goreddit.Post{ID: uuid.New(), ThreadID: uuid.New(), Title: "Orphan", Content: "Orphan"}
+	checkNotFound(t, "CreatePost in unknown thread", s.CreatePost(ctx, &orphan))
+
+	dup := goreddit.Post{ID: p.ID, ThreadID: th.ID, Title: "Duplicate", Content: "Duplicate"}
+	if err := s.CreatePost(ctx, &dup); !errors.Is(err, goreddit.ErrConflict) {
+		t.Errorf("CreatePost with existing id: got error %v, want ErrConflict", err)
+	}
+
+	// Replies count as comments of the post
+	c := createComment(t, s, p.ID, uuid.NullUUID{}, "First", 0)
+	createComment(t, s, p.ID, valid(c.ID), "Reply", 0)
+	createComment(t, s, otherPost.ID, uuid.NullUUID{}, "Elsewhere", 0)
+
+	pp, _, err := s.PostsByThread(ctx, th.ID, goreddit.PostSort{Sort: goreddit.SortNew}, goreddit.Page{})
+	if err != nil {
+		t.Fatalf("PostsByThread: %v", err)
+	}
+	checkIDs(t, "PostsByThread", postIDs(pp), []uuid.UUID{p.ID})
+	if len(pp) == 1 && (pp[0].CommentsCount != 2 || pp[0].Author != "alice" || pp[0].Votes != 3) {
+		t.Errorf("PostsByThread returned comments count %d, author %q and votes %d, want 2, %q and 3",
+			pp[0].CommentsCount, pp[0].Author, pp[0].Votes, "alice")
+	}
+
+	pp, _, err = s.PostsByThread(ctx, uuid.New(), goreddit.PostSort{Sort: goreddit.SortNew}, goreddit.Page{})
+	if err != nil || len(pp) != 0 {
+		t.Errorf("PostsByThread of unknown thread returned %d posts and error %v, want none", len(pp), err)
+	}
+
+	pp, _, err = s.Posts(ctx, goreddit.PostSort{Sort: goreddit.SortTop, Period: goreddit.PeriodAll}, goreddit.Page{})
+	if err != nil {
+		t.Fatalf("Posts: %v", err)
+	}
+	checkIDs(t, "Posts", postIDs(pp), []uuid.UUID{p.ID, otherPost.ID})
+	if len(pp) == 2 {
+		if pp[0].ThreadTitle != "Go" || pp[1].ThreadTitle != "Rust" {
+			t.Errorf("Posts returned thread titles %q, %q, want %q, %q", pp[0].ThreadTitle, pp[1].ThreadTitle, "Go", "Rust")
+		}
+		if pp[0].CommentsCount != 2 || pp[1].CommentsCount != 1 {
+			t.Errorf("Posts returned comments counts %d, %d, want 2, 1", pp[0].CommentsCount, pp[1].CommentsCount)
+		}
+	}
+
+	tick()
+	p.Title = "Generics in Go"
+	p.Content = "Finally here"
+	p.Votes = 5 // Only votes change the score
+	if err := s.UpdatePost(ctx, &p); err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+	if !p.UpdatedAt.After(p.CreatedAt) {
+		t.Errorf("UpdatePost set UpdatedAt %v, want after CreatedAt %v", p.UpdatedAt, p.CreatedAt)
+	}
+	got, err = s.Post(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if got.Title != "Generics in Go" || got.Content != "Finally here" || got.Votes != 3 {
+		t.Errorf("Post after update returned %q, %q, %d, want %q, %q, 3", got.Title, got.Content, got.Votes, "Generics in Go", "Finally here")
+	}
+
+	// Posts can move to another thread, but not to a missing one
+	p.ThreadID = other.ID
+	if err := s.UpdatePost(ctx, &p); err != nil {
+		t.Fatalf("UpdatePost to other thread: %v", err)
+	}
+	pp, _, err = s.PostsByThread(ctx, other.ID, goreddit.PostSort{Sort: goreddit.SortTop}, goreddit.Page{})
+	if err != nil {
+		t.Fatalf("PostsByThread: %v", err)
+	}
+	checkIDs(t, "PostsByThread after moving a post", postIDs(pp), []uuid.UUID{p.ID, otherPost.ID})
+
+	p.ThreadID = uuid.New()
+	checkNotFound(t, "UpdatePost to unknown thread", s.UpdatePost(ctx, &p))
+
+	missing := goreddit.Post{ID: uuid.New(), ThreadID: th.ID, Title: "Missing", Content: "Missing"}
+	checkNotFound(t, "UpdatePost of unknown id", s.UpdatePost(ctx, &missing))
+
+	if err := s.DeletePost(ctx, p.ID); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+	_, err = s.Post(ctx, p.ID)
+	checkNotFound(t, "Post after delete", err)
+	checkNotFound(t, "DeletePost of deleted post", s.DeletePost(ctx, p.ID))
+}
+
+func testPostsSort(t *testing.T, s goreddit.Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	th := createThread(t, s, "Go", uuid.NullUUID{})
+
+	popular := createPost(t, s, th.ID, "Popular", 100)
+	tick()
+	disliked := createPost(t, s, th.ID, "Disliked", -5)
+	tick()
+	fresh := createPost(t, s, th.ID, "Fresh", 0)
+
+	// Split votes make a post controversial
+	if err := s.VotePost(ctx, alice.ID, disliked.ID, 1); err != nil {
+		t.Fatalf("VotePost: %v", err)
+	}
+	if err := s.VotePost(ctx, bob.ID, disliked.ID, -1); err != nil {
+		t.Fatalf("VotePost: %v", err)
+	}
+	if err := s.VotePost(ctx, alice.ID, popular.ID, 1); err != nil {
+		t.Fatalf("VotePost: %v", err)
+	}
+
+	tests := []struct {
+		sort goreddit.PostSort
+		want []uuid.UUID
+	}{
+		{goreddit.PostSort{Sort: goreddit.SortNew}, []uuid.UUID{fresh.ID, disliked.ID, popular.ID}},
+		{goreddit.PostSort{Sort: goreddit.SortTop, Period: goreddit.PeriodAll}, []uuid.UUID{popular.ID, fresh.ID, disliked.ID}},
+		{goreddit.PostSort{Sort: goreddit.SortTop, Period: goreddit.PeriodHour}, []uuid.UUID{popular.ID, fresh.ID, disliked.ID}},
+		{goreddit.PostSort{Sort: goreddit.SortTop, Period: goreddit.PeriodYear}, []uuid.UUID{popular.ID, fresh.ID, disliked.ID}},
+		// Posts of the same age are ranked by their votes
+		{goreddit.PostSort{Sort: goreddit.SortHot}, []uuid.UUID{popular.ID, fresh.ID, disliked.ID}},
+	}
+	for _, tt := range tests {
+		pp, _, err := s.PostsByThread(ctx, th.ID, tt.sort, goreddit.Page{})
+		if err != nil {
+			t.Fatalf("PostsByThread sorted by %v: %v", tt.sort, err)
+		}
+		checkIDs(t, "PostsByThread sorted by "+string(tt.sort.Sort)+" "+string(tt.sort.Period), postIDs(pp), tt.want)
+
+		pp, _, err = s.Posts(ctx, tt.sort, goreddit.Page{})
+		if err != nil {
+			t.Fatalf("Posts sorted by %v: %v", tt.sort, err)
+		}
+		checkIDs(t, "Posts sorted by "+string(tt.sort.Sort)+" "+string(tt.sort.Period), postIDs(pp), tt.want)
+	}
+
+	// The posts without split votes tie, so only the first one is known
+	pp, _, err := s.Posts(ctx, goreddit.PostSort{Sort: goreddit.SortControversial, Period: goreddit.PeriodDay}, goreddit.Page{})
+	if err != nil {
+		t.Fatalf("Posts sorted by controversial: %v", err)
+	}
+	if len(pp) != 3 || pp[0].ID != disliked.ID {
+		t.Errorf("Posts sorted by controversial returned %v, want %v first of 3", postIDs(pp), disliked.ID)
+	}
+}
+
+func testPostsPagination(t *testing.T, s goreddit.Store) {
+	th := createThread(t, s, "Go", uuid.NullUUID{})
+	other := createThread(t, s, "Rust", uuid.NullUUID{})
+
+	// Equal votes make sure that ties are paginated consistently
+	for i, votes := range []int{3, 1, 3, 0, 1, 3, 2} {
+		createPost(t, s, th.ID, "Go post", votes)
+		if i%2 == 0 {
+			createPost(t, s, other.ID, "Rust post", votes)
+		}
+		tick()
+	}
+
+	for _, sort := range []goreddit.Sort{goreddit.SortHot, goreddit.SortNew, goreddit.SortTop, goreddit.SortControversial} {
+		postSort := goreddit.PostSort{Sort: sort, Period: goreddit.PeriodAll}
+
+		pp, info, err := s.PostsByThread(ctx, th.ID, postSort, goreddit.Page{})
+		if err != nil {
+			t.Fatalf("PostsByThread sorted by %s: %v", sort, err)
+		}
+		if len(pp) != 7 || info != (goreddit.PageInfo{}) {
+			t.Errorf("PostsByThread sorted by %s returned %d posts and cursors %+v, want 7 posts on one page", sort, len(pp), info)
+		}
+		got := walkPages(t, func(page goreddit.Page) ([]uuid.UUID, goreddit.PageInfo, error) {
+			pp, info, err := s.PostsByThread(ctx, th.ID, postSort, page)
+			return postIDs(pp), info, err
+		}, 3)
+		checkIDs(t, "PostsByThread by pages sorted by "+string(sort), got, postIDs(pp))
+
+		pp, _, err = s.Posts(ctx, postSort, goreddit.Page{})
+		if err != nil {
+			t.Fatalf("Posts sorted by %s: %v", sort, err)
+		}
+		if len(pp) != 11 {
+			t.Errorf("Posts sorted by %s returned %d posts, want 11", sort, len(pp))
+		}
+		got = walkPages(t, func(page goreddit.Page) ([]uuid.UUID, goreddit.PageInfo, error) {
+			pp, info, err := s.Posts(ctx, postSort, page)
+			return postIDs(pp), info, err
+		}, 4)
+		checkIDs(t, "Posts by pages sorted by "+string(sort), got, postIDs(pp))
+	}
+}
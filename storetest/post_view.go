@@ -0,0 +1,111 @@
+package storetest
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+func testPostView(t *testing.T, s goreddit.Store) {
+	alice := createUser(t, s, "alice")
+	th := createThread(t, s, "Go", valid(alice.ID))
+	p := goreddit.Post{ID: uuid.New(), ThreadID: th.ID, UserID: valid(alice.ID), Title: "Generics", Content: "Finally", Votes: 2}
+	if err := s.CreatePost(ctx, &p); err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+
+	// A post without comments still comes with its thread
+	v, err := s.PostView(ctx, p.ID, uuid.NullUUID{}, goreddit.SortTop, 10, goreddit.Page{})
+	if err != nil {
+		t.Fatalf("PostView: %v", err)
+	}
+	if v.Post.ID != p.ID || v.Post.Title != p.Title || v.Post.Votes != p.Votes || v.Post.Author != "alice" ||
+		!sameTime(v.Post.CreatedAt, p.CreatedAt) {
+		t.Errorf("PostView returned post %+v, want %+v by alice", v.Post, p)
+	}
+	if v.Thread.ID != th.ID || v.Thread.Title != th.Title || v.Thread.Author != "alice" {
+		t.Errorf("PostView returned thread %+v, want %+v by alice", v.Thread, th)
+	}
+	if len(v.Comments) != 0 || v.PageInfo != (goreddit.PageInfo{}) {
+		t.Errorf("PostView of a post without comments returned %d comments and cursors %+v", len(v.Comments), v.PageInfo)
+	}
+
+	// a (1)          d (5)
+	// └── b (3)      └── e (0)
+	//     └── c (0)
+	a := goreddit.Comment{ID: uuid.New(), PostID: p.ID, UserID: valid(alice.ID), Content: "a", Votes: 1}
+	if err := s.CreateComment(ctx, &a); err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+	tick()
+	b := createComment(t, s, p.ID, valid(a.ID), "b", 3)
+	tick()
+	createComment(t, s, p.ID, valid(b.ID), "c", 0)
+	tick()
+	d := createComment(t, s, p.ID, uuid.NullUUID{}, "d", 5)
+	tick()
+	createComment(t, s, p.ID, valid(d.ID), "e", 0)
+
+	// The comments are the ones CommentTree returns for the same arguments
+	tests := []struct {
+		name     string
+		root     uuid.NullUUID
+		sort     goreddit.Sort
+		maxDepth int
+		page     goreddit.Page
+	}{
+		{"top", uuid.NullUUID{}, goreddit.SortTop, 10, goreddit.Page{}},
+		{"new", uuid.NullUUID{}, goreddit.SortNew, 10, goreddit.Page{}},
+		{"max depth", uuid.NullUUID{}, goreddit.SortTop, 2, goreddit.Page{}},
+		{"from reply", valid(b.ID), goreddit.SortTop, 10, goreddit.Page{}},
+		{"from unknown comment", valid(uuid.New()), goreddit.SortTop, 10, goreddit.Page{}},
+		{"first page", uuid.NullUUID{}, goreddit.SortTop, 10, goreddit.Page{Limit: 1}},
+	}
+	for _, tt := range tests {
+		want, wantInfo, err := s.CommentTree(ctx, p.ID, tt.root, tt.sort, tt.maxDepth, tt.page)
+		if err != nil {
+			t.Fatalf("CommentTree %s: %v", tt.name, err)
+		}
+		v, err := s.PostView(ctx, p.ID, tt.root, tt.sort, tt.maxDepth, tt.page)
+		if err != nil {
+			t.Fatalf("PostView %s: %v", tt.name, err)
+		}
+
+		if v.Post.ID != p.ID || v.Thread.ID != th.ID {
+			t.Errorf("PostView %s returned post %s in thread %s, want %s in %s", tt.name, v.Post.ID, v.Thread.ID, p.ID, th.ID)
+		}
+		if v.PageInfo != wantInfo {
+			t.Errorf("PostView %s returned cursors %+v, want %+v", tt.name, v.PageInfo, wantInfo)
+		}
+		checkIDs(t, "PostView "+tt.name, commentIDs(v.Comments), commentIDs(want))
+		if len(v.Comments) != len(want) {
+			continue
+		}
+		for i, c := range v.Comments {
+			w := want[i]
+			if c.PostID != w.PostID || c.ParentID != w.ParentID || c.UserID != w.UserID || c.Content != w.Content ||
+				c.Votes != w.Votes || c.Author != w.Author || c.Depth != w.Depth || c.RepliesCount != w.RepliesCount ||
+				!sameTime(c.CreatedAt, w.CreatedAt) || !sameTime(c.UpdatedAt, w.UpdatedAt) {
+				t.Errorf("PostView %s returned %+v at %d, want %+v", tt.name, c, i, w)
+			}
+		}
+
+		// The next page matches as well
+		if v.PageInfo.Next != "" {
+			next := goreddit.Page{After: v.PageInfo.Next, Limit: tt.page.Limit}
+			want, _, err := s.CommentTree(ctx, p.ID, tt.root, tt.sort, tt.maxDepth, next)
+			if err != nil {
+				t.Fatalf("CommentTree %s: %v", tt.name, err)
+			}
+			v, err := s.PostView(ctx, p.ID, tt.root, tt.sort, tt.maxDepth, next)
+			if err != nil {
+				t.Fatalf("PostView %s: %v", tt.name, err)
+			}
+			checkIDs(t, "PostView "+tt.name+" next page", commentIDs(v.Comments), commentIDs(want))
+		}
+	}
+
+	_, err = s.PostView(ctx, uuid.New(), uuid.NullUUID{}, goreddit.SortTop, 10, goreddit.Page{})
+	checkNotFound(t, "PostView of unknown post", err)
+}
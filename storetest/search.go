@@ -0,0 +1,88 @@
+package storetest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+func testSearch(t *testing.T, s goreddit.Store) {
+	th := createThread(t, s, "Gophers", uuid.NullUUID{})
+	other := createThread(t, s, "Crabs", uuid.NullUUID{})
+	p := createPost(t, s, th.ID, "Generics", 0)
+	c := createComment(t, s, p.ID, uuid.NullUUID{}, "Gophers love generics", 0)
+	otherPost := createPost(t, s, other.ID, "Generics for crabs", 0)
+	createPost(t, s, other.ID, "Lifetimes", 0)
+
+	rr, _, err := s.Search(ctx, "generics", uuid.NullUUID{}, goreddit.Page{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	found := map[uuid.UUID]goreddit.SearchResult{}
+	for _, r := range rr {
+		found[r.ID] = r
+	}
+	if len(rr) != 3 || len(found) != 3 {
+		t.Fatalf("Search returned %d results, want 3", len(rr))
+	}
+
+	if r := found[p.ID]; r.Kind != "post" || r.ThreadID != th.ID || r.PostID != valid(p.ID) {
+		t.Errorf("Search returned post %+v, want kind post in thread %v", r, th.ID)
+	}
+	if r := found[c.ID]; r.Kind != "comment" || r.ThreadID != th.ID || r.PostID != valid(p.ID) {
+		t.Errorf("Search returned comment %+v, want kind comment of post %v", r, p.ID)
+	}
+	if r := found[otherPost.ID]; !strings.Contains(r.Title, goreddit.HighlightStart) {
+		t.Errorf("Search returned title %q without highlighted words", r.Title)
+	}
+	if r := found[c.ID]; !strings.Contains(r.Snippet, goreddit.HighlightStart+"generics"+goreddit.HighlightStop) {
+		t.Errorf("Search returned snippet %q, want %q highlighted", r.Snippet, "generics")
+	}
+	for i := 1; i < len(rr); i++ {
+		if rr[i].Rank > rr[i-1].Rank {
+			t.Errorf("Search returned rank %v after %v, want best matches first", rr[i].Rank, rr[i-1].Rank)
+		}
+	}
+
+	rr, _, err = s.Search(ctx, "generics", valid(th.ID), goreddit.Page{})
+	if err != nil {
+		t.Fatalf("Search in thread: %v", err)
+	}
+	var ids []uuid.UUID
+	for _, r := range rr {
+		ids = append(ids, r.ID)
+		if r.ThreadID != th.ID {
+			t.Errorf("Search in thread %v returned result of thread %v", th.ID, r.ThreadID)
+		}
+	}
+	if len(ids) != 2 {
+		t.Errorf("Search in thread returned %v, want %v and %v", ids, p.ID, c.ID)
+	}
+
+	rr, _, err = s.Search(ctx, "generics -crabs", uuid.NullUUID{}, goreddit.Page{})
+	if err != nil {
+		t.Fatalf("Search excluding a word: %v", err)
+	}
+	if len(rr) != 2 {
+		t.Errorf("Search excluding a word returned %d results, want 2", len(rr))
+	}
+
+	rr, _, err = s.Search(ctx, "unicorns", uuid.NullUUID{}, goreddit.Page{})
+	if err != nil || len(rr) != 0 {
+		t.Errorf("Search without matches returned %d results and error %v, want none", len(rr), err)
+	}
+
+	got := walkPages(t, func(page goreddit.Page) ([]uuid.UUID, goreddit.PageInfo, error) {
+		rr, info, err := s.Search(ctx, "generics", uuid.NullUUID{}, page)
+		ids := make([]uuid.UUID, len(rr))
+		for i, r := range rr {
+			ids[i] = r.ID
+		}
+		return ids, info, err
+	}, 1)
+	if len(got) != 3 {
+		t.Errorf("Search by pages of 1 returned %d results, want 3", len(got))
+	}
+}
@@ -0,0 +1,127 @@
+// Package storetest checks that an implementation of goreddit.Store behaves
+// the way the handlers expect. Every backend runs the same tests from its
+// own test file:
+//
+//	func TestStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) goreddit.Store {
+//			return NewStore()
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// Factory returns an empty store for a single test. It may register
+// cleanups with t and should skip the test if the backend is unavailable.
+type Factory func(t *testing.T) goreddit.Store
+
+// Run runs the conformance tests as subtests of t, each with a new store from
+// newStore
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s goreddit.Store)
+	}{
+		{"Threads", testThreads},
+		{"ThreadsPagination", testThreadsPagination},
+		{"Posts", testPosts},
+		{"PostsSort", testPostsSort},
+		{"PostsPagination", testPostsPagination},
+		{"Comments", testComments},
+		{"CommentTree", testCommentTree},
+		{"PostView", testPostView},
+		{"CascadingDeletes", testCascadingDeletes},
+		{"Users", testUsers},
+		{"Votes", testVotes},
+		{"VotesDuringUpdate", testVotesDuringUpdate},
+		{"Moderators", testModerators},
+		{"Search", testSearch},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+// ctx is the context of all store calls
+var ctx = context.Background()
+
+// timeTolerance is how much the stored timestamps may differ from the
+// timestamps set by the Create and Update methods, as databases round them
+const timeTolerance = time.Millisecond
+
+// sameTime reports whether a and b are equal within timeTolerance
+func sameTime(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d < timeTolerance && d > -timeTolerance
+}
+
+// checkNotFound fails the test unless err is goreddit.ErrNotFound
+func checkNotFound(t *testing.T, what string, err error) {
+	t.Helper()
+	if !errors.Is(err, goreddit.ErrNotFound) {
+		t.Errorf("%s: got error %v, want ErrNotFound", what, err)
+	}
+}
+
+// tick waits long enough for the next created item to be newer than the
+// previous one in every backend
+func tick() {
+	time.Sleep(5 * time.Millisecond)
+}
+
+// createUser creates a user with the username
+func createUser(t *testing.T, s goreddit.Store, username string) goreddit.User {
+	t.Helper()
+	u := goreddit.User{ID: uuid.New(), Username: username, Password: "hashed password"}
+	if err := s.CreateUser(ctx, &u); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return u
+}
+
+// createThread creates a thread by the user, if valid
+func createThread(t *testing.T, s goreddit.Store, title string, userID uuid.NullUUID) goreddit.Thread {
+	t.Helper()
+	th := goreddit.Thread{ID: uuid.New(), UserID: userID, Title: title, Description: "About " + title}
+	if err := s.CreateThread(ctx, &th); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	return th
+}
+
+// createPost creates a post with the votes in the thread
+func createPost(t *testing.T, s goreddit.Store, threadID uuid.UUID, title string, votes int) goreddit.Post {
+	t.Helper()
+	p := goreddit.Post{ID: uuid.New(), ThreadID: threadID, Title: title, Content: "Content of " + title, Votes: votes}
+	if err := s.CreatePost(ctx, &p); err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	return p
+}
+
+// createComment creates a comment with the votes on the post, replying to
+// the parent comment if it is valid
+func createComment(t *testing.T, s goreddit.Store, postID uuid.UUID, parentID uuid.NullUUID, content string, votes int) goreddit.Comment {
+	t.Helper()
+	c := goreddit.Comment{ID: uuid.New(), PostID: postID, ParentID: parentID, Content: content, Votes: votes}
+	if err := s.CreateComment(ctx, &c); err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+	return c
+}
+
+// valid returns id as a valid uuid.NullUUID
+func valid(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: true}
+}
@@ -0,0 +1,111 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+func testThreads(t *testing.T, s goreddit.Store) {
+	u := createUser(t, s, "alice")
+
+	before := time.Now()
+	th := createThread(t, s, "Go", valid(u.ID))
+	if th.CreatedAt.Before(before.Add(-time.Second)) || th.CreatedAt.After(time.Now().Add(time.Second)) {
+		t.Errorf("CreateThread set CreatedAt to %v, want about %v", th.CreatedAt, before)
+	}
+	if !th.UpdatedAt.Equal(th.CreatedAt) {
+		t.Errorf("CreateThread set UpdatedAt %v, want CreatedAt %v", th.UpdatedAt, th.CreatedAt)
+	}
+
+	got, err := s.Thread(ctx, th.ID)
+	if err != nil {
+		t.Fatalf("Thread: %v", err)
+	}
+	if got.ID != th.ID || got.Title != "Go" || got.Description != "About Go" || got.UserID != valid(u.ID) {
+		t.Errorf("Thread returned %+v, want %+v", got, th)
+	}
+	if got.Author != "alice" {
+		t.Errorf("Thread returned author %q, want %q", got.Author, "alice")
+	}
+	if !sameTime(got.CreatedAt, th.CreatedAt) || !sameTime(got.UpdatedAt, th.UpdatedAt) {
+		t.Errorf("Thread returned times %v, %v, want %v, %v", got.CreatedAt, got.UpdatedAt, th.CreatedAt, th.UpdatedAt)
+	}
+
+	_, err = s.Thread(ctx, uuid.New())
+	checkNotFound(t, "Thread of unknown id", err)
+
+	dup := goreddit.Thread{ID: th.ID, Title: "Duplicate", Description: "Duplicate"}
+	if err := s.CreateThread(ctx, &dup); !errors.Is(err, goreddit.ErrConflict) {
+		t.Errorf("CreateThread with existing id: got error %v, want ErrConflict", err)
+	}
+
+	tick()
+	th.Title = "Golang"
+	th.Description = "All things Go"
+	if err := s.UpdateThread(ctx, &th); err != nil {
+		t.Fatalf("UpdateThread: %v", err)
+	}
+	if !th.UpdatedAt.After(th.CreatedAt) {
+		t.Errorf("UpdateThread set UpdatedAt %v, want after CreatedAt %v", th.UpdatedAt, th.CreatedAt)
+	}
+	got, err = s.Thread(ctx, th.ID)
+	if err != nil {
+		t.Fatalf("Thread: %v", err)
+	}
+	if got.Title != "Golang" || got.Description != "All things Go" {
+		t.Errorf("Thread after update returned %q, %q, want %q, %q", got.Title, got.Description, "Golang", "All things Go")
+	}
+	if !sameTime(got.CreatedAt, th.CreatedAt) || !sameTime(got.UpdatedAt, th.UpdatedAt) {
+		t.Errorf("Thread after update returned times %v, %v, want %v, %v", got.CreatedAt, got.UpdatedAt, th.CreatedAt, th.UpdatedAt)
+	}
+
+	missing := goreddit.Thread{ID: uuid.New(), Title: "Missing", Description: "Missing"}
+	checkNotFound(t, "UpdateThread of unknown id", s.UpdateThread(ctx, &missing))
+
+	if err := s.DeleteThread(ctx, th.ID); err != nil {
+		t.Fatalf("DeleteThread: %v", err)
+	}
+	_, err = s.Thread(ctx, th.ID)
+	checkNotFound(t, "Thread after delete", err)
+	checkNotFound(t, "DeleteThread of deleted thread", s.DeleteThread(ctx, th.ID))
+}
+
+func testThreadsPagination(t *testing.T, s goreddit.Store) {
+	tt, info, err := s.Threads(ctx, goreddit.Page{})
+	if err != nil {
+		t.Fatalf("Threads: %v", err)
+	}
+	if len(tt) != 0 || info != (goreddit.PageInfo{}) {
+		t.Errorf("Threads of empty store returned %d threads and %+v", len(tt), info)
+	}
+
+	// Newest first
+	var want []uuid.UUID
+	for _, title := range []string{"a", "b", "c", "d", "e"} {
+		want = append([]uuid.UUID{createThread(t, s, title, uuid.NullUUID{}).ID}, want...)
+		tick()
+	}
+
+	tt, info, err = s.Threads(ctx, goreddit.Page{})
+	if err != nil {
+		t.Fatalf("Threads: %v", err)
+	}
+	checkIDs(t, "Threads", threadIDs(tt), want)
+	if info != (goreddit.PageInfo{}) {
+		t.Errorf("Threads on a single page returned cursors %+v", info)
+	}
+
+	got := walkPages(t, func(page goreddit.Page) ([]uuid.UUID, goreddit.PageInfo, error) {
+		tt, info, err := s.Threads(ctx, page)
+		return threadIDs(tt), info, err
+	}, 2)
+	checkIDs(t, "Threads by pages of 2", got, want)
+
+	if _, _, err := s.Threads(ctx, goreddit.Page{After: "not a cursor"}); !errors.Is(err, goreddit.ErrInvalidCursor) {
+		t.Errorf("Threads with an invalid cursor returned error %v, want %v", err, goreddit.ErrInvalidCursor)
+	}
+}
@@ -0,0 +1,99 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+func testUsers(t *testing.T, s goreddit.Store) {
+	u := createUser(t, s, "alice")
+
+	got, err := s.User(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	if got != u {
+		t.Errorf("User returned %+v, want %+v", got, u)
+	}
+	got, err = s.UserByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("UserByUsername: %v", err)
+	}
+	if got != u {
+		t.Errorf("UserByUsername returned %+v, want %+v", got, u)
+	}
+
+	_, err = s.User(ctx, uuid.New())
+	checkNotFound(t, "User of unknown id", err)
+	_, err = s.UserByUsername(ctx, "bob")
+	checkNotFound(t, "UserByUsername of unknown username", err)
+
+	taken := goreddit.User{ID: uuid.New(), Username: "alice", Password: "other password"}
+	if err := s.CreateUser(ctx, &taken); !errors.Is(err, goreddit.ErrConflict) {
+		t.Errorf("CreateUser with taken username: got error %v, want ErrConflict", err)
+	}
+
+	bob := createUser(t, s, "bob")
+	bob.Username = "alice"
+	if err := s.UpdateUser(ctx, &bob); !errors.Is(err, goreddit.ErrConflict) {
+		t.Errorf("UpdateUser to taken username: got error %v, want ErrConflict", err)
+	}
+	bob.Username = "robert"
+	bob.Password = "new password"
+	if err := s.UpdateUser(ctx, &bob); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	got, err = s.UserByUsername(ctx, "robert")
+	if err != nil {
+		t.Fatalf("UserByUsername after update: %v", err)
+	}
+	if got != bob {
+		t.Errorf("UserByUsername after update returned %+v, want %+v", got, bob)
+	}
+
+	missing := goreddit.User{ID: uuid.New(), Username: "carol", Password: "password"}
+	checkNotFound(t, "UpdateUser of unknown id", s.UpdateUser(ctx, &missing))
+
+	// The content of deleted users stays without an author
+	th := createThread(t, s, "Go", valid(u.ID))
+	p := goreddit.Post{ID: uuid.New(), ThreadID: th.ID, UserID: valid(u.ID), Title: "Generics", Content: "Finally"}
+	if err := s.CreatePost(ctx, &p); err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	c := goreddit.Comment{ID: uuid.New(), PostID: p.ID, UserID: valid(u.ID), Content: "Nice"}
+	if err := s.CreateComment(ctx, &c); err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+
+	if err := s.DeleteUser(ctx, u.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	_, err = s.User(ctx, u.ID)
+	checkNotFound(t, "User after delete", err)
+	checkNotFound(t, "DeleteUser of deleted user", s.DeleteUser(ctx, u.ID))
+
+	gotThread, err := s.Thread(ctx, th.ID)
+	if err != nil {
+		t.Fatalf("Thread of deleted user: %v", err)
+	}
+	if gotThread.UserID.Valid || gotThread.Author != "" {
+		t.Errorf("Thread of deleted user has user %v and author %q, want none", gotThread.UserID, gotThread.Author)
+	}
+	gotPost, err := s.Post(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("Post of deleted user: %v", err)
+	}
+	if gotPost.UserID.Valid || gotPost.Author != "" {
+		t.Errorf("Post of deleted user has user %v and author %q, want none", gotPost.UserID, gotPost.Author)
+	}
+	gotComment, err := s.Comment(ctx, c.ID)
+	if err != nil {
+		t.Fatalf("Comment of deleted user: %v", err)
+	}
+	if gotComment.UserID.Valid {
+		t.Errorf("Comment of deleted user has user %v, want none", gotComment.UserID)
+	}
+}
@@ -0,0 +1,124 @@
+package storetest
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+func testVotes(t *testing.T, s goreddit.Store) {
+	alice := createUser(t, s, "alice")
+	bob := createUser(t, s, "bob")
+	th := createThread(t, s, "Go", uuid.NullUUID{})
+	p := createPost(t, s, th.ID, "Generics", 10)
+	other := createPost(t, s, th.ID, "Modules", 0)
+	c := createComment(t, s, p.ID, uuid.NullUUID{}, "Nice", 10)
+
+	// Each user has one vote, changing it adjusts the score by the difference
+	steps := []struct {
+		user  uuid.UUID
+		value int
+		want  int
+	}{
+		{alice.ID, 1, 11},
+		{alice.ID, 1, 11},
+		{bob.ID, 1, 12},
+		{alice.ID, -1, 10},
+		{bob.ID, 0, 9},
+	}
+	for _, step := range steps {
+		if err := s.VotePost(ctx, step.user, p.ID, step.value); err != nil {
+			t.Fatalf("VotePost: %v", err)
+		}
+		got, err := s.Post(ctx, p.ID)
+		if err != nil {
+			t.Fatalf("Post: %v", err)
+		}
+		if got.Votes != step.want {
+			t.Errorf("VotePost %d made %d votes, want %d", step.value, got.Votes, step.want)
+		}
+
+		if err := s.VoteComment(ctx, step.user, c.ID, step.value); err != nil {
+			t.Fatalf("VoteComment: %v", err)
+		}
+		gotComment, err := s.Comment(ctx, c.ID)
+		if err != nil {
+			t.Fatalf("Comment: %v", err)
+		}
+		if gotComment.Votes != step.want {
+			t.Errorf("VoteComment %d made %d votes, want %d", step.value, gotComment.Votes, step.want)
+		}
+	}
+
+	vv, err := s.PostVotes(ctx, alice.ID, []uuid.UUID{p.ID, other.ID})
+	if err != nil {
+		t.Fatalf("PostVotes: %v", err)
+	}
+	if vv[p.ID] != -1 || vv[other.ID] != 0 {
+		t.Errorf("PostVotes returned %v, want -1 for %v and nothing else", vv, p.ID)
+	}
+	vv, err = s.CommentVotes(ctx, alice.ID, []uuid.UUID{c.ID})
+	if err != nil {
+		t.Fatalf("CommentVotes: %v", err)
+	}
+	if vv[c.ID] != -1 {
+		t.Errorf("CommentVotes returned %v, want -1 for %v", vv, c.ID)
+	}
+	vv, err = s.PostVotes(ctx, alice.ID, nil)
+	if err != nil || len(vv) != 0 {
+		t.Errorf("PostVotes of no posts returned %v and error %v, want none", vv, err)
+	}
+
+	if err := s.VotePost(ctx, alice.ID, p.ID, 2); err == nil {
+		t.Errorf("VotePost with value 2 returned no error")
+	}
+	checkNotFound(t, "VotePost on unknown post", s.VotePost(ctx, alice.ID, uuid.New(), 1))
+	checkNotFound(t, "VoteComment on unknown comment", s.VoteComment(ctx, alice.ID, uuid.New(), 1))
+}
+
+// testVotesDuringUpdate votes on a post and a comment after they were loaded
+// for editing, as a handler does, and checks that saving the edit keeps the
+// vote
+func testVotesDuringUpdate(t *testing.T, s goreddit.Store) {
+	alice := createUser(t, s, "alice")
+	th := createThread(t, s, "Go", uuid.NullUUID{})
+	created := createPost(t, s, th.ID, "Generics", 10)
+	createdComment := createComment(t, s, created.ID, uuid.NullUUID{}, "Nice", 10)
+
+	p, err := s.Post(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if err := s.VotePost(ctx, alice.ID, p.ID, 1); err != nil {
+		t.Fatalf("VotePost: %v", err)
+	}
+	p.Title = "Generics in Go"
+	if err := s.UpdatePost(ctx, &p); err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+	if p.Votes != 11 {
+		t.Errorf("UpdatePost returned %d votes, want 11", p.Votes)
+	}
+	if got, err := s.Post(ctx, p.ID); err != nil || got.Votes != 11 {
+		t.Errorf("Post after update returned %d votes and error %v, want 11", got.Votes, err)
+	}
+
+	c, err := s.Comment(ctx, createdComment.ID)
+	if err != nil {
+		t.Fatalf("Comment: %v", err)
+	}
+	if err := s.VoteComment(ctx, alice.ID, c.ID, -1); err != nil {
+		t.Fatalf("VoteComment: %v", err)
+	}
+	c.Content = "Very nice"
+	if err := s.UpdateComment(ctx, &c); err != nil {
+		t.Fatalf("UpdateComment: %v", err)
+	}
+	if c.Votes != 9 {
+		t.Errorf("UpdateComment returned %d votes, want 9", c.Votes)
+	}
+	if got, err := s.Comment(ctx, c.ID); err != nil || got.Votes != 9 {
+		t.Errorf("Comment after update returned %d votes and error %v, want 9", got.Votes, err)
+	}
+}
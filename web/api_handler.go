@@ -0,0 +1,843 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/gorilla/csrf"
+	"github.com/nahuakang/goreddit"
+)
+
+// APIHandler handles the JSON API under /api/v1
+//
+// API clients authenticate with HTTP Basic auth using their username and
+// password. Session cookies are ignored so that the API needs no CSRF tokens.
+type APIHandler struct {
+	store    goreddit.Store
+	sessions *scs.SessionManager
+}
+
+// Routes returns the router of the JSON API
+func (h *APIHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(h.authenticate)
+
+	r.Route("/threads", func(r chi.Router) {
+		r.Get("/", h.ListThreads())
+		r.With(h.requireUser).Post("/", h.CreateThread())
+		r.Get("/{threadID}", h.GetThread())
+		r.With(h.requireUser).Put("/{threadID}", h.UpdateThread())
+		r.With(h.requireUser).Delete("/{threadID}", h.DeleteThread())
+		r.Get("/{threadID}/posts", h.ListPosts())
+		r.With(h.requireUser).Post("/{threadID}/posts", h.CreatePost())
+	})
+	r.Route("/posts", func(r chi.Router) {
+		r.Get("/", h.ListPosts())
+		r.Get("/{postID}", h.GetPost())
+		r.With(h.requireUser).Put("/{postID}", h.UpdatePost())
+		r.With(h.requireUser).Delete("/{postID}", h.DeletePost())
+		r.With(h.requireUser).Post("/{postID}/vote", h.VotePost())
+		r.Get("/{postID}/comments", h.ListComments())
+		r.With(h.requireUser).Post("/{postID}/comments", h.CreateComment())
+	})
+	r.Route("/comments", func(r chi.Router) {
+		r.Get("/{commentID}", h.GetComment())
+		r.With(h.requireUser).Put("/{commentID}", h.UpdateComment())
+		r.With(h.requireUser).Delete("/{commentID}", h.DeleteComment())
+		r.With(h.requireUser).Post("/{commentID}/vote", h.VoteComment())
+	})
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		apiError(w, http.StatusNotFound, "not_found", "The requested resource does not exist.")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		apiError(w, http.StatusMethodNotAllowed, "method_not_allowed", "The method is not allowed for this resource.")
+	})
+
+	return r
+}
+
+// skipAPICSRF disables the CSRF check for API requests. It must be used
+// before csrf.Protect.
+func skipAPICSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			r = csrf.UnsafeSkipCheck(r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate replaces the session user in the request context with the
+// user given by the HTTP Basic auth credentials, if any
+func (h *APIHandler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), userContextKey, nil)
+
+		username, password, ok := r.BasicAuth()
+		if ok {
+			user, err := h.store.UserByUsername(r.Context(), username)
+			if !checkPassword(user, err, password) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="goreddit"`)
+				apiError(w, http.StatusUnauthorized, "unauthorized", "Username or password is incorrect.")
+				return
+			}
+			ctx = context.WithValue(ctx, userContextKey, user)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireUser rejects requests without valid credentials
+func (h *APIHandler) requireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := userFromContext(r.Context()); !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="goreddit"`)
+			apiError(w, http.StatusUnauthorized, "unauthorized", "Please authenticate with your username and password.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiThread is the JSON representation of a goreddit.Thread
+type apiThread struct {
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	AuthorID    *uuid.UUID `json:"author_id"`
+	Author      string     `json:"author,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// apiPost is the JSON representation of a goreddit.Post
+type apiPost struct {
+	ID            uuid.UUID  `json:"id"`
+	ThreadID      uuid.UUID  `json:"thread_id"`
+	ThreadTitle   string     `json:"thread_title,omitempty"`
+	Title         string     `json:"title"`
+	Content       string     `json:"content"`
+	Votes         int        `json:"votes"`
+	CommentsCount int        `json:"comments_count"`
+	AuthorID      *uuid.UUID `json:"author_id"`
+	Author        string     `json:"author,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// apiComment is the JSON representation of a goreddit.Comment
+type apiComment struct {
+	ID        uuid.UUID  `json:"id"`
+	PostID    uuid.UUID  `json:"post_id"`
+	ParentID  *uuid.UUID `json:"parent_id"`
+	Content   string     `json:"content"`
+	Votes     int        `json:"votes"`
+	AuthorID  *uuid.UUID `json:"author_id"`
+	Author    string     `json:"author,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// apiPagination contains the cursors of the pages next to a list
+type apiPagination struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// apiList is the envelope of list responses
+type apiList struct {
+	Data       interface{}   `json:"data"`
+	Pagination apiPagination `json:"pagination"`
+}
+
+// apiItem is the envelope of single resource responses
+type apiItem struct {
+	Data interface{} `json:"data"`
+}
+
+// apiErrorBody is the envelope of error responses
+type apiErrorBody struct {
+	Error struct {
+		Code    string     `json:"code"`
+		Message string     `json:"message"`
+		Fields  FormErrors `json:"fields,omitempty"`
+	} `json:"error"`
+}
+
+// nullUUID returns a pointer to the id if it is valid, or nil
+func nullUUID(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+// newAPIThread converts a thread to its JSON representation
+func newAPIThread(t goreddit.Thread) apiThread {
+	return apiThread{
+		ID:          t.ID,
+		Title:       t.Title,
+		Description: t.Description,
+		AuthorID:    nullUUID(t.UserID),
+		Author:      t.Author,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+}
+
+// newAPIPost converts a post to its JSON representation
+func newAPIPost(p goreddit.Post) apiPost {
+	return apiPost{
+		ID:            p.ID,
+		ThreadID:      p.ThreadID,
+		ThreadTitle:   p.ThreadTitle,
+		Title:         p.Title,
+		Content:       p.Content,
+		Votes:         p.Votes,
+		CommentsCount: p.CommentsCount,
+		AuthorID:      nullUUID(p.UserID),
+		Author:        p.Author,
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
+	}
+}
+
+// newAPIComment converts a comment to its JSON representation
+func newAPIComment(c goreddit.Comment) apiComment {
+	return apiComment{
+		ID:        c.ID,
+		PostID:    c.PostID,
+		ParentID:  nullUUID(c.ParentID),
+		Content:   c.Content,
+		Votes:     c.Votes,
+		AuthorID:  nullUUID(c.UserID),
+		Author:    c.Author,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+// ListThreads returns a page of threads
+func (h *APIHandler) ListThreads() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tt, info, err := h.store.Threads(r.Context(), pageFromQuery(r))
+		if err != nil {
+			apiStoreError(w, err)
+			return
+		}
+
+		data := make([]apiThread, len(tt))
+		for i, t := range tt {
+			data[i] = newAPIThread(t)
+		}
+		writeJSON(w, http.StatusOK, apiList{Data: data, Pagination: apiPagination(info)})
+	}
+}
+
+// GetThread returns a thread
+func (h *APIHandler) GetThread() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, ok := h.thread(w, r)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, apiItem{Data: newAPIThread(t)})
+	}
+}
+
+// CreateThread creates a thread from a JSON body with title and description
+func (h *APIHandler) CreateThread() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+		}
+		if !decodeJSON(w, r, &body) {
+			return
+		}
+		form := CreateThreadForm{Title: body.Title, Description: body.Description}
+		if !form.Validate() {
+			apiValidationError(w, form.Errors)
+			return
+		}
+		if !h.permit(w, r, uuid.Nil, uuid.NullUUID{}, goreddit.PermCreateThread) {
+			return
+		}
+
+		user, _ := userFromContext(r.Context())
+		t := &goreddit.Thread{
+			ID:          uuid.New(),
+			UserID:      uuid.NullUUID{UUID: user.ID, Valid: true},
+			Title:       form.Title,
+			Description: form.Description,
+		}
+		if err := h.store.CreateThread(r.Context(), t); err != nil {
+			apiStoreError(w, err)
+			return
+		}
+
+		t.Author = user.Username
+		writeJSON(w, http.StatusCreated, apiItem{Data: newAPIThread(*t)})
+	}
+}
+
+// UpdateThread updates the title and description of a thread
+func (h *APIHandler) UpdateThread() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, ok := h.thread(w, r)
+		if !ok || !h.authorize(w, r, t.ID, t.UserID) {
+			return
+		}
+
+		var body struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+		}
+		if !decodeJSON(w, r, &body) {
+			return
+		}
+		form := CreateThreadForm{Title: body.Title, Description: body.Description}
+		if !form.Validate() {
+			apiValidationError(w, form.Errors)
+			return
+		}
+
+		t.Title = form.Title
+		t.Description = form.Description
+		if err := h.store.UpdateThread(r.Context(), &t); err != nil {
+			apiStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, apiItem{Data: newAPIThread(t)})
+	}
+}
+
+// DeleteThread deletes a thread with all its posts and comments, which its
+// author and site admins may do
+func (h *APIHandler) DeleteThread() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, ok := h.thread(w, r)
+		if !ok || !h.permit(w, r, t.ID, t.UserID, goreddit.PermDeleteThread) {
+			return
+		}
+
+		if err := h.store.DeleteThread(r.Context(), t.ID); err != nil {
+			apiStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListPosts returns a page of the posts of all threads, or of the thread in
+// the URL, sorted by ?sort= and ?t=
+func (h *APIHandler) ListPosts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var pp []goreddit.Post
+		var info goreddit.PageInfo
+		var err error
+		if chi.URLParam(r, "threadID") != "" {
+			t, ok := h.thread(w, r)
+			if !ok {
+				return
+			}
+			pp, info, err = h.store.PostsByThread(r.Context(), t.ID, postSortFromQuery(r), pageFromQuery(r))
+		} else {
+			pp, info, err = h.store.Posts(r.Context(), postSortFromQuery(r), pageFromQuery(r))
+		}
+		if err != nil {
+			apiStoreError(w, err)
+			return
+		}
+
+		data := make([]apiPost, len(pp))
+		for i, p := range pp {
+			data[i] = newAPIPost(p)
+		}
+		writeJSON(w, http.StatusOK, apiList{Data: data, Pagination: apiPagination(info)})
+	}
+}
+
+// GetPost returns a post
+func (h *APIHandler) GetPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := h.post(w, r)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, apiItem{Data: newAPIPost(p)})
+	}
+}
+
+// CreatePost creates a post in the thread of the URL from a JSON body with
+// title and content
+func (h *APIHandler) CreatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, ok := h.thread(w, r)
+		if !ok || !h.permit(w, r, t.ID, uuid.NullUUID{}, goreddit.PermPost) {
+			return
+		}
+
+		var body struct {
+			Title   string `json:"title"`
+			Content string `json:"content"`
+		}
+		if !decodeJSON(w, r, &body) {
+			return
+		}
+		form := CreatePostForm{Title: body.Title, Content: body.Content}
+		if !form.Validate() {
+			apiValidationError(w, form.Errors)
+			return
+		}
+
+		user, _ := userFromContext(r.Context())
+		p := &goreddit.Post{
+			ID:       uuid.New(),
+			ThreadID: t.ID,
+			UserID:   uuid.NullUUID{UUID: user.ID, Valid: true},
+			Title:    form.Title,
+			Content:  form.Content,
+		}
+		if err := h.store.CreatePost(r.Context(), p); err != nil {
+			apiStoreError(w, err)
+			return
+		}
+
+		p.ThreadTitle = t.Title
+		p.Author = user.Username
+		writeJSON(w, http.StatusCreated, apiItem{Data: newAPIPost(*p)})
+	}
+}
+
+// UpdatePost updates the title and content of a post
+func (h *APIHandler) UpdatePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := h.post(w, r)
+		if !ok || !h.permit(w, r, p.ThreadID, p.UserID, goreddit.PermEditPost) {
+			return
+		}
+
+		var body struct {
+			Title   string `json:"title"`
+			Content string `json:"content"`
+		}
+		if !decodeJSON(w, r, &body) {
+			return
+		}
+		form := CreatePostForm{Title: body.Title, Content: body.Content}
+		if !form.Validate() {
+			apiValidationError(w, form.Errors)
+			return
+		}
+
+		p.Title = form.Title
+		p.Content = form.Content
+		if err := h.store.UpdatePost(r.Context(), &p); err != nil {
+			apiStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, apiItem{Data: newAPIPost(p)})
+	}
+}
+
+// DeletePost deletes a post with all its comments, which its author and the
+// moderators of its thread may do
+func (h *APIHandler) DeletePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := h.post(w, r)
+		if !ok || !h.permit(w, r, p.ThreadID, p.UserID, goreddit.PermRemovePost) {
+			return
+		}
+
+		if err := h.store.DeletePost(r.Context(), p.ID); err != nil {
+			apiStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// VotePost sets the vote of the user on a post from a JSON body with a vote
+// of -1, 0 or 1
+func (h *APIHandler) VotePost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := h.post(w, r)
+		if !ok || !h.permit(w, r, p.ThreadID, uuid.NullUUID{}, goreddit.PermPost) {
+			return
+		}
+
+		value, ok := decodeVote(w, r)
+		if !ok {
+			return
+		}
+
+		user, _ := userFromContext(r.Context())
+		if err := h.store.VotePost(r.Context(), user.ID, p.ID, value); err != nil {
+			apiStoreError(w, err)
+			return
+		}
+
+		p, err := h.store.Post(r.Context(), p.ID)
+		if err != nil {
+			apiStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, apiItem{Data: voteResponse{Votes: p.Votes, Vote: value}})
+	}
+}
+
+// ListComments returns a page of the comments of a post, best first
+func (h *APIHandler) ListComments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := h.post(w, r)
+		if !ok {
+			return
+		}
+
+		cc, info, err := h.store.CommentsByPost(r.Context(), p.ID, pageFromQuery(r))
+		if err != nil {
+			apiStoreError(w, err)
+			return
+		}
+
+		data := make([]apiComment, len(cc))
+		for i, c := range cc {
+			data[i] = newAPIComment(c)
+		}
+		writeJSON(w, http.StatusOK, apiList{Data: data, Pagination: apiPagination(info)})
+	}
+}
+
+// GetComment returns a comment
+func (h *APIHandler) GetComment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := h.comment(w, r)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, apiItem{Data: newAPIComment(c)})
+	}
+}
+
+// CreateComment creates a comment on the post of the URL from a JSON body
+// with content and an optional parent_id to reply to
+func (h *APIHandler) CreateComment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := h.post(w, r)
+		if !ok || !h.permit(w, r, p.ThreadID, uuid.NullUUID{}, goreddit.PermPost) {
+			return
+		}
+
+		var body struct {
+			Content  string     `json:"content"`
+			ParentID *uuid.UUID `json:"parent_id"`
+		}
+		if !decodeJSON(w, r, &body) {
+			return
+		}
+		form := CreateCommentForm{Content: body.Content}
+		if !form.Validate() {
+			apiValidationError(w, form.Errors)
+			return
+		}
+
+		var parentID uuid.NullUUID
+		if body.ParentID != nil {
+			parent, err := h.store.Comment(r.Context(), *body.ParentID)
+			if err != nil || parent.PostID != p.ID {
+				apiValidationError(w, FormErrors{"ParentID": "The parent comment does not belong to this post."})
+				return
+			}
+			parentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		}
+
+		user, _ := userFromContext(r.Context())
+		c := &goreddit.Comment{
+			ID:       uuid.New(),
+			PostID:   p.ID,
+			ParentID: parentID,
+			UserID:   uuid.NullUUID{UUID: user.ID, Valid: true},
+			Content:  form.Content,
+		}
+		if err := h.store.CreateComment(r.Context(), c); err != nil {
+			apiStoreError(w, err)
+			return
+		}
+
+		c.Author = user.Username
+		writeJSON(w, http.StatusCreated, apiItem{Data: newAPIComment(*c)})
+	}
+}
+
+// UpdateComment updates the content of a comment
+func (h *APIHandler) UpdateComment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := h.comment(w, r)
+		if !ok {
+			return
+		}
+		threadID, ok := h.commentThread(w, r, c)
+		if !ok || !h.permit(w, r, threadID, c.UserID, goreddit.PermEditComment) {
+			return
+		}
+
+		var body struct {
+			Content string `json:"content"`
+		}
+		if !decodeJSON(w, r, &body) {
+			return
+		}
+		form := CreateCommentForm{Content: body.Content}
+		if !form.Validate() {
+			apiValidationError(w, form.Errors)
+			return
+		}
+
+		c.Content = form.Content
+		if err := h.store.UpdateComment(r.Context(), &c); err != nil {
+			apiStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, apiItem{Data: newAPIComment(c)})
+	}
+}
+
+// DeleteComment deletes a comment with all its replies, which its author and
+// the moderators of its thread may do
+func (h *APIHandler) DeleteComment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := h.comment(w, r)
+		if !ok {
+			return
+		}
+		threadID, ok := h.commentThread(w, r, c)
+		if !ok || !h.permit(w, r, threadID, c.UserID, goreddit.PermRemoveComment) {
+			return
+		}
+
+		if err := h.store.DeleteComment(r.Context(), c.ID); err != nil {
+			apiStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// VoteComment sets the vote of the user on a comment from a JSON body with a
+// vote of -1, 0 or 1
+func (h *APIHandler) VoteComment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := h.comment(w, r)
+		if !ok {
+			return
+		}
+		threadID, ok := h.commentThread(w, r, c)
+		if !ok || !h.permit(w, r, threadID, uuid.NullUUID{}, goreddit.PermPost) {
+			return
+		}
+
+		value, ok := decodeVote(w, r)
+		if !ok {
+			return
+		}
+
+		user, _ := userFromContext(r.Context())
+		if err := h.store.VoteComment(r.Context(), user.ID, c.ID, value); err != nil {
+			apiStoreError(w, err)
+			return
+		}
+
+		c, err := h.store.Comment(r.Context(), c.ID)
+		if err != nil {
+			apiStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, apiItem{Data: voteResponse{Votes: c.Votes, Vote: value}})
+	}
+}
+
+// thread loads the thread of the {threadID} URL parameter, writing an error
+// response if that fails
+func (h *APIHandler) thread(w http.ResponseWriter, r *http.Request) (goreddit.Thread, bool) {
+	id, ok := apiURLParamUUID(w, r, "threadID")
+	if !ok {
+		return goreddit.Thread{}, false
+	}
+
+	t, err := h.store.Thread(r.Context(), id)
+	if err != nil {
+		apiStoreError(w, err)
+		return goreddit.Thread{}, false
+	}
+	return t, true
+}
+
+// post loads the post of the {postID} URL parameter, writing an error
+// response if that fails
+func (h *APIHandler) post(w http.ResponseWriter, r *http.Request) (goreddit.Post, bool) {
+	id, ok := apiURLParamUUID(w, r, "postID")
+	if !ok {
+		return goreddit.Post{}, false
+	}
+
+	p, err := h.store.Post(r.Context(), id)
+	if err != nil {
+		apiStoreError(w, err)
+		return goreddit.Post{}, false
+	}
+	return p, true
+}
+
+// comment loads the comment of the {commentID} URL parameter, writing an
+// error response if that fails
+func (h *APIHandler) comment(w http.ResponseWriter, r *http.Request) (goreddit.Comment, bool) {
+	id, ok := apiURLParamUUID(w, r, "commentID")
+	if !ok {
+		return goreddit.Comment{}, false
+	}
+
+	c, err := h.store.Comment(r.Context(), id)
+	if err != nil {
+		apiStoreError(w, err)
+		return goreddit.Comment{}, false
+	}
+	return c, true
+}
+
+// apiURLParamUUID parses a URL parameter as UUID. Invalid ids cannot exist,
+// so they are reported as not found.
+func apiURLParamUUID(w http.ResponseWriter, r *http.Request, key string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, key))
+	if err != nil {
+		apiError(w, http.StatusNotFound, "not_found", "The requested resource does not exist.")
+		return uuid.UUID{}, false
+	}
+	return id, true
+}
+
+// authorize checks that the authenticated user may modify content written by
+// the user with authorID in the thread, which authors may do unless they were
+// banned from it, writing an error response if not
+func (h *APIHandler) authorize(w http.ResponseWriter, r *http.Request, threadID uuid.UUID, authorID uuid.NullUUID) bool {
+	if !canModify(r.Context(), authorID) {
+		apiError(w, http.StatusForbidden, "forbidden", "Only the author may do this.")
+		return false
+	}
+
+	role, err := userRole(r.Context(), h.store, threadID)
+	if err != nil {
+		apiStoreError(w, err)
+		return false
+	}
+	if role == goreddit.RoleBanned {
+		apiError(w, http.StatusForbidden, "forbidden", "You are banned from this thread.")
+		return false
+	}
+	return true
+}
+
+// permit checks that the authenticated user wrote the content of authorID,
+// if valid, or has the permission in the thread, writing an error response
+// if not. Users banned from the thread are refused either way.
+func (h *APIHandler) permit(w http.ResponseWriter, r *http.Request, threadID uuid.UUID, authorID uuid.NullUUID, perm goreddit.Permission) bool {
+	role, err := userRole(r.Context(), h.store, threadID)
+	if err != nil {
+		apiStoreError(w, err)
+		return false
+	}
+	if role != goreddit.RoleBanned && canModify(r.Context(), authorID) {
+		return true
+	}
+	if !role.Can(perm) {
+		msg := "You are not allowed to do this."
+		if role == goreddit.RoleBanned {
+			msg = "You are banned from this thread."
+		}
+		apiError(w, http.StatusForbidden, "forbidden", msg)
+		return false
+	}
+	return true
+}
+
+// commentThread gets the id of the thread of the comment c, writing an error
+// response if that fails
+func (h *APIHandler) commentThread(w http.ResponseWriter, r *http.Request, c goreddit.Comment) (uuid.UUID, bool) {
+	p, err := h.store.Post(r.Context(), c.PostID)
+	if err != nil {
+		apiStoreError(w, err)
+		return uuid.Nil, false
+	}
+	return p.ThreadID, true
+}
+
+// decodeJSON decodes the JSON request body into v, writing an error response
+// if that fails
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		apiError(w, http.StatusBadRequest, "invalid_json", "The request body is not valid JSON: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// decodeVote decodes a JSON request body with a vote of -1, 0 or 1, writing
+// an error response if that fails
+func decodeVote(w http.ResponseWriter, r *http.Request) (int, bool) {
+	var body struct {
+		Vote int `json:"vote"`
+	}
+	if !decodeJSON(w, r, &body) {
+		return 0, false
+	}
+	if body.Vote < -1 || body.Vote > 1 {
+		apiValidationError(w, FormErrors{"Vote": "The vote must be -1, 0 or 1."})
+		return 0, false
+	}
+	return body.Vote, true
+}
+
+// apiStoreError writes the error response of a failed store call
+func apiStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, goreddit.ErrNotFound) {
+		apiError(w, http.StatusNotFound, "not_found", "The requested resource does not exist.")
+		return
+	}
+	if errors.Is(err, goreddit.ErrConflict) {
+		apiError(w, http.StatusConflict, "conflict", "The resource conflicts with an existing one.")
+		return
+	}
+	if errors.Is(err, goreddit.ErrInvalidCursor) {
+		apiError(w, http.StatusBadRequest, "invalid_cursor", "The page cursor is invalid.")
+		return
+	}
+	log.Printf("api: %v", err)
+	apiError(w, http.StatusInternalServerError, "internal_error", "Something went wrong on our side.")
+}
+
+// apiValidationError writes the error response of invalid input
+func apiValidationError(w http.ResponseWriter, fields FormErrors) {
+	var body apiErrorBody
+	body.Error.Code = "validation_failed"
+	body.Error.Message = "Some fields are invalid."
+	body.Error.Fields = fields
+	writeJSON(w, http.StatusUnprocessableEntity, body)
+}
+
+// apiError writes an error response in the API error envelope
+func apiError(w http.ResponseWriter, status int, code, message string) {
+	var body apiErrorBody
+	body.Error.Code = code
+	body.Error.Message = message
+	writeJSON(w, status, body)
+}
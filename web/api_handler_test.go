@@ -0,0 +1,106 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// votingStore lets bob vote on posts and comments right after they are
+// loaded, as if his vote came in while the loaded copy is being edited
+type votingStore struct {
+	goreddit.Store
+}
+
+func (s votingStore) Post(ctx context.Context, id uuid.UUID) (goreddit.Post, error) {
+	p, err := s.Store.Post(ctx, id)
+	if err != nil {
+		return p, err
+	}
+	return p, s.VotePost(ctx, bobID, id, 1)
+}
+
+func (s votingStore) Comment(ctx context.Context, id uuid.UUID) (goreddit.Comment, error) {
+	c, err := s.Store.Comment(ctx, id)
+	if err != nil {
+		return c, err
+	}
+	return c, s.VoteComment(ctx, bobID, id, 1)
+}
+
+func TestAPIUpdateKeepsVotes(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		body      string
+		get       func(s goreddit.Store) (int, error)
+		wantVotes int
+	}{
+		{"post", "/api/v1/posts/" + postID.String(), `{"title":"Generics in Go","content":"Finally here"}`,
+			func(s goreddit.Store) (int, error) {
+				p, err := s.Post(context.Background(), postID)
+				return p.Votes, err
+			}, 4},
+		{"comment", "/api/v1/comments/" + commentID.String(), `{"content":"Really great news"}`,
+			func(s goreddit.Store) (int, error) {
+				c, err := s.Comment(context.Background(), commentID)
+				return c.Votes, err
+			}, 3},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			c := newTestClient(t, votingStore{store})
+
+			req, err := http.NewRequest(http.MethodPut, c.server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.SetBasicAuth("alice", testPassword)
+			res := c.do(req)
+			if res.status != http.StatusOK {
+				t.Fatalf("got status %d, want %d: %s", res.status, http.StatusOK, res.body)
+			}
+
+			var body struct {
+				Data struct {
+					Votes int `json:"votes"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal([]byte(res.body), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Data.Votes != tt.wantVotes {
+				t.Errorf("PUT returned %d votes, want %d", body.Data.Votes, tt.wantVotes)
+			}
+			if votes, err := tt.get(store); err != nil || votes != tt.wantVotes {
+				t.Errorf("got %d votes and error %v after PUT, want %d", votes, err, tt.wantVotes)
+			}
+		})
+	}
+}
+
+func TestAPIDeleteAsBannedUser(t *testing.T) {
+	for _, path := range []string{
+		"/api/v1/posts/" + davePostID.String(),
+		"/api/v1/comments/" + daveCommentID.String(),
+	} {
+		store := newTestStore(t)
+		c := newTestClient(t, store)
+
+		req, err := http.NewRequest(http.MethodDelete, c.server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("dave", testPassword)
+		if res := c.do(req); res.status != http.StatusForbidden || !strings.Contains(res.body, "You are banned from this thread.") {
+			t.Errorf("DELETE %s as banned author: got status %d, want %d: %s", path, res.status, http.StatusForbidden, res.body)
+		}
+	}
+}
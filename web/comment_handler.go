@@ -1,11 +1,13 @@
 package web
 
 import (
+	"html/template"
 	"net/http"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
+	"github.com/gorilla/csrf"
 	"github.com/nahuakang/goreddit"
 )
 
@@ -13,27 +15,68 @@ import (
 type CommentHandler struct {
 	store    goreddit.Store
 	sessions *scs.SessionManager
+	errs     *ErrorHandler
 }
 
 // Store saves the newly created comments of a post to database
 func (h *CommentHandler) Store() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		content := r.FormValue("content")
+		form := CreateCommentForm{
+			Content:  r.FormValue("content"),
+			ParentID: r.FormValue("parent_id"),
+		}
+		if !form.Validate() {
+			h.sessions.Put(r.Context(), "form", form)
+			http.Redirect(w, r, r.Referer(), http.StatusFound)
+			return
+		}
 
 		idStr := chi.URLParam(r, "postID")
 
 		id, err := uuid.Parse(idStr)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.errs.NotFound(w, r)
 			return
 		}
 
-		if err := h.store.CreateComment(&goreddit.Comment{
-			ID:      uuid.New(),
-			PostID:  id,
-			Content: content,
+		// The role of the user was checked in the thread of the URL
+		p, err := h.store.Post(r.Context(), id)
+		if err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+		if p.ThreadID.String() != chi.URLParam(r, "threadID") {
+			h.errs.NotFound(w, r)
+			return
+		}
+
+		// Replies must belong to the same post as their parent comment
+		var parentID uuid.NullUUID
+		if form.ParentID != "" {
+			pid, err := uuid.Parse(form.ParentID)
+			if err != nil {
+				http.Error(w, "Invalid parent comment", http.StatusBadRequest)
+				return
+			}
+
+			parent, err := h.store.Comment(r.Context(), pid)
+			if err != nil || parent.PostID != id {
+				http.Error(w, "Invalid parent comment", http.StatusBadRequest)
+				return
+			}
+			parentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		}
+
+		user, _ := userFromContext(r.Context())
+
+		if err := h.store.CreateComment(r.Context(), &goreddit.Comment{
+			ID:       uuid.New(),
+			PostID:   id,
+			ParentID: parentID,
+			UserID:   uuid.NullUUID{UUID: user.ID, Valid: true},
+			Content:  form.Content,
 		}); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.errs.Error(w, r, err)
 			return
 		}
 
@@ -50,28 +93,175 @@ func (h *CommentHandler) Vote() http.HandlerFunc {
 
 		id, err := uuid.Parse(idStr)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.errs.NotFound(w, r)
+			return
+		}
+
+		c, err := h.store.Comment(r.Context(), id)
+		if err != nil {
+			h.errs.Error(w, r, err)
 			return
 		}
 
-		c, err := h.store.Comment(id)
+		user, _ := userFromContext(r.Context())
+
+		votes, err := h.store.CommentVotes(r.Context(), user.ID, []uuid.UUID{c.ID})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		value, ok := voteValue(r.FormValue("dir"), votes[c.ID])
+		if !ok {
+			http.Error(w, "Invalid vote direction", http.StatusBadRequest)
 			return
 		}
 
-		dir := r.URL.Query().Get("dir")
-		if dir == "up" {
-			c.Votes++
-		} else if dir == "down" {
-			c.Votes--
+		if err := h.store.VoteComment(r.Context(), user.ID, c.ID, value); err != nil {
+			h.errs.Error(w, r, err)
+			return
 		}
 
-		if err := h.store.UpdateComment(&c); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if wantsJSON(r) {
+			c, err := h.store.Comment(r.Context(), c.ID)
+			if err != nil {
+				h.errs.Error(w, r, err)
+				return
+			}
+
+			writeJSON(w, http.StatusOK, voteResponse{Votes: c.Votes, Vote: value})
 			return
 		}
 
 		http.Redirect(w, r, r.Referer(), http.StatusFound)
 	}
 }
+
+// Edit leads to the page for editing a comment
+func (h *CommentHandler) Edit() http.HandlerFunc {
+	type data struct {
+		SessionData
+		CSRF    template.HTML
+		Post    goreddit.Post
+		Comment goreddit.Comment
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := h.modifiableComment(w, r)
+		if !ok {
+			return
+		}
+
+		p, err := h.store.Post(r.Context(), c.PostID)
+		if err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		h.errs.Render(w, r, "comment_edit.html", data{
+			SessionData: GetSessionData(r.Context(), h.sessions),
+			CSRF:        csrf.TemplateField(r),
+			Post:        p,
+			Comment:     c,
+		})
+	}
+}
+
+// Update saves the changes to an edited comment to database
+func (h *CommentHandler) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := h.modifiableComment(w, r)
+		if !ok {
+			return
+		}
+
+		form := CreateCommentForm{
+			Content: r.FormValue("content"),
+		}
+		if !form.Validate() {
+			h.sessions.Put(r.Context(), "form", form)
+			http.Redirect(w, r, r.URL.Path, http.StatusFound)
+			return
+		}
+
+		c.Content = form.Content
+
+		if err := h.store.UpdateComment(r.Context(), &c); err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		p, err := h.store.Post(r.Context(), c.PostID)
+		if err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		h.sessions.Put(r.Context(), "flash", "Your comment has been updated.")
+
+		http.Redirect(w, r, "/threads/"+p.ThreadID.String()+"/"+p.ID.String(), http.StatusFound)
+	}
+}
+
+// Delete deletes a comment, which its author and the moderators of its
+// thread may do
+func (h *CommentHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := h.comment(w, r)
+		if !ok {
+			return
+		}
+
+		if !canChange(r.Context(), c.UserID, goreddit.PermRemoveComment) {
+			http.Error(w, "You may only delete your own comments", http.StatusForbidden)
+			return
+		}
+
+		p, err := h.store.Post(r.Context(), c.PostID)
+		if err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		if err := h.store.DeleteComment(r.Context(), c.ID); err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		h.sessions.Put(r.Context(), "flash", "The comment has been deleted.")
+
+		http.Redirect(w, r, "/threads/"+p.ThreadID.String()+"/"+p.ID.String(), http.StatusFound)
+	}
+}
+
+// modifiableComment gets the comment of the request, writing an error
+// response if it does not exist or the logged in user may not modify it
+func (h *CommentHandler) modifiableComment(w http.ResponseWriter, r *http.Request) (goreddit.Comment, bool) {
+	c, ok := h.comment(w, r)
+	if !ok {
+		return goreddit.Comment{}, false
+	}
+
+	if !canChange(r.Context(), c.UserID, goreddit.PermEditComment) {
+		http.Error(w, "You may only change your own comments", http.StatusForbidden)
+		return goreddit.Comment{}, false
+	}
+	return c, true
+}
+
+// comment gets the comment of the request, writing an error response if it
+// does not exist
+func (h *CommentHandler) comment(w http.ResponseWriter, r *http.Request) (goreddit.Comment, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.errs.NotFound(w, r)
+		return goreddit.Comment{}, false
+	}
+
+	c, err := h.store.Comment(r.Context(), id)
+	if err != nil {
+		h.errs.Error(w, r, err)
+		return goreddit.Comment{}, false
+	}
+	return c, true
+}
@@ -0,0 +1,95 @@
+package web
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi"
+	"github.com/nahuakang/goreddit"
+)
+
+// ErrorHandler handles failed requests
+type ErrorHandler struct {
+	sessions  *scs.SessionManager
+	templates *Templates
+}
+
+// NewErrorHandler constructs a new ErrorHandler pointer rendering the pages
+// of templates
+func NewErrorHandler(sessions *scs.SessionManager, templates *Templates) *ErrorHandler {
+	return &ErrorHandler{
+		sessions:  sessions,
+		templates: templates,
+	}
+}
+
+// Render writes the page with the data as the response. A page that fails to
+// render is logged with the route and the error page is written instead, so
+// that no half-written page is sent.
+func (h *ErrorHandler) Render(w http.ResponseWriter, r *http.Request, page string, data interface{}) {
+	h.render(w, r, http.StatusOK, page, data)
+}
+
+// render writes the page with the status, or the error page if it fails
+func (h *ErrorHandler) render(w http.ResponseWriter, r *http.Request, status int, page string, data interface{}) {
+	if err := h.templates.Render(w, status, page, data); err != nil {
+		log.Printf("%s %s: Error rendering %s: %v", r.Method, route(r), page, err)
+		h.internalError(w, r)
+	}
+}
+
+// NotFound renders the page for missing threads, posts, comments and routes
+func (h *ErrorHandler) NotFound(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, http.StatusNotFound, "404.html", GetSessionData(r.Context(), h.sessions))
+}
+
+// Error writes the response of a failed request, which is the not found page
+// for goreddit.ErrNotFound, the conflict page for goreddit.ErrConflict and a
+// redirect to the first page of the listing for goreddit.ErrInvalidCursor.
+// Other errors are logged rather than shown, as they may reveal details about
+// the database.
+func (h *ErrorHandler) Error(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, goreddit.ErrNotFound) {
+		h.NotFound(w, r)
+		return
+	}
+	if errors.Is(err, goreddit.ErrConflict) {
+		h.render(w, r, http.StatusConflict, "409.html", GetSessionData(r.Context(), h.sessions))
+		return
+	}
+	if errors.Is(err, goreddit.ErrInvalidCursor) {
+		q := r.URL.Query()
+		q.Del("after")
+		q.Del("before")
+		u := *r.URL
+		u.RawQuery = q.Encode()
+		http.Redirect(w, r, u.RequestURI(), http.StatusFound)
+		return
+	}
+
+	log.Printf("%s %s: %v", r.Method, route(r), err)
+	h.internalError(w, r)
+}
+
+// internalError renders the page for server errors. It leaves the session
+// alone, so that the flash message and form values survive for the next page.
+func (h *ErrorHandler) internalError(w http.ResponseWriter, r *http.Request) {
+	var data SessionData
+	data.User, data.LoggedIn = userFromContext(r.Context())
+
+	if err := h.templates.Render(w, http.StatusInternalServerError, "500.html", data); err != nil {
+		log.Printf("%s %s: Error rendering 500.html: %v", r.Method, route(r), err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// route returns the route pattern that matched the request, such as
+// /threads/{id}, or the path if no route matched
+func route(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		return rctx.RoutePattern()
+	}
+	return r.URL.Path
+}
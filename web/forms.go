@@ -1,15 +1,109 @@
 package web
 
-import "encoding/gob"
+import (
+	"encoding/gob"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
 
 func init() {
+	gob.Register(CreateThreadForm{})
 	gob.Register(CreatePostForm{})
+	gob.Register(CreateCommentForm{})
+	gob.Register(RegisterForm{})
+	gob.Register(LoginForm{})
 	gob.Register(FormErrors{})
 }
 
 // FormErrors store errors for validating forms
 type FormErrors map[string]string
 
+// Limits on the length of form values, counted in characters
+const (
+	threadTitleMaxLength       = 100
+	threadDescriptionMaxLength = 500
+	postTitleMaxLength         = 300
+	postContentMaxLength       = 40000
+	commentContentMaxLength    = 10000
+	usernameMaxLength          = 30
+	passwordMinLength          = 8
+)
+
+// Required records an error for field if value is empty
+func (e FormErrors) Required(field, value, message string) {
+	if value == "" {
+		e.add(field, message)
+	}
+}
+
+// MinLength records an error for field if value is shorter than n characters
+func (e FormErrors) MinLength(field, value string, n int) {
+	if utf8.RuneCountInString(value) < n {
+		e.add(field, fmt.Sprintf("This must be at least %d characters long.", n))
+	}
+}
+
+// MaxLength records an error for field if value is longer than n characters
+func (e FormErrors) MaxLength(field, value string, n int) {
+	if utf8.RuneCountInString(value) > n {
+		e.add(field, fmt.Sprintf("This must be at most %d characters long.", n))
+	}
+}
+
+// NoBannedChars records an error for field if value contains control or
+// text direction characters, except for line breaks and tabs
+func (e FormErrors) NoBannedChars(field, value string) {
+	if strings.IndexFunc(value, bannedChar) >= 0 {
+		e.add(field, "This contains characters that are not allowed.")
+	}
+}
+
+// add records the message for field unless it already has an error, so that
+// the first failed check is the one shown
+func (e FormErrors) add(field, message string) {
+	if _, ok := e[field]; !ok {
+		e[field] = message
+	}
+}
+
+// bannedChar reports whether r may not be used in form values
+func bannedChar(r rune) bool {
+	switch {
+	case r == '\n' || r == '\r' || r == '\t':
+		return false
+	case unicode.IsControl(r):
+		return true
+	case r >= '\u202A' && r <= '\u202E', r >= '\u2066' && r <= '\u2069':
+		return true
+	}
+	return false
+}
+
+// CreateThreadForm stores form values for new and edited threads
+type CreateThreadForm struct {
+	Title       string
+	Description string
+	Errors      FormErrors
+}
+
+// Validate validates the thread forms
+func (f *CreateThreadForm) Validate() bool {
+	f.Errors = FormErrors{}
+	f.Title = strings.TrimSpace(f.Title)
+	f.Description = strings.TrimSpace(f.Description)
+
+	f.Errors.Required("Title", f.Title, "Please enter a title.")
+	f.Errors.MaxLength("Title", f.Title, threadTitleMaxLength)
+	f.Errors.NoBannedChars("Title", f.Title)
+	f.Errors.Required("Description", f.Description, "Please enter a description.")
+	f.Errors.MaxLength("Description", f.Description, threadDescriptionMaxLength)
+	f.Errors.NoBannedChars("Description", f.Description)
+
+	return len(f.Errors) == 0
+}
+
 // CreatePostForm stores form values for new posts
 type CreatePostForm struct {
 	Title   string
@@ -20,13 +114,83 @@ type CreatePostForm struct {
 // Validate valites the post forms
 func (f *CreatePostForm) Validate() bool {
 	f.Errors = FormErrors{}
+	f.Title = strings.TrimSpace(f.Title)
+	f.Content = strings.TrimSpace(f.Content)
+
+	f.Errors.Required("Title", f.Title, "Please enter a title.")
+	f.Errors.MaxLength("Title", f.Title, postTitleMaxLength)
+	f.Errors.NoBannedChars("Title", f.Title)
+	f.Errors.Required("Content", f.Content, "Please enter a text.")
+	f.Errors.MaxLength("Content", f.Content, postContentMaxLength)
+	f.Errors.NoBannedChars("Content", f.Content)
+
+	return len(f.Errors) == 0
+}
+
+// CreateCommentForm stores form values for new and edited comments
+type CreateCommentForm struct {
+	Content  string
+	ParentID string
+	Errors   FormErrors
+}
+
+// Validate validates the comment forms
+func (f *CreateCommentForm) Validate() bool {
+	f.Errors = FormErrors{}
+	f.Content = strings.TrimSpace(f.Content)
+
+	f.Errors.Required("Content", f.Content, "Please enter a text.")
+	f.Errors.MaxLength("Content", f.Content, commentContentMaxLength)
+	f.Errors.NoBannedChars("Content", f.Content)
+
+	return len(f.Errors) == 0
+}
 
-	if f.Title == "" {
-		f.Errors["Title"] = "Please enter a title."
+// RegisterForm stores form values for new users. Its Password must be
+// cleared before the form is put in the session.
+type RegisterForm struct {
+	Username      string
+	Password      string
+	UsernameTaken bool
+	Errors        FormErrors
+}
+
+// Validate validates the register forms
+func (f *RegisterForm) Validate() bool {
+	f.Errors = FormErrors{}
+	f.Username = strings.TrimSpace(f.Username)
+
+	f.Errors.Required("Username", f.Username, "Please enter a username.")
+	f.Errors.MaxLength("Username", f.Username, usernameMaxLength)
+	f.Errors.NoBannedChars("Username", f.Username)
+	if f.UsernameTaken {
+		f.Errors.add("Username", "This username is already taken.")
 	}
-	if f.Content == "" {
-		f.Errors["Content"] = "Please enter a text."
+	f.Errors.Required("Password", f.Password, "Please enter a password.")
+	f.Errors.MinLength("Password", f.Password, passwordMinLength)
+
+	return len(f.Errors) == 0
+}
+
+// LoginForm stores form values for logging in. Its Password must be cleared
+// before the form is put in the session.
+type LoginForm struct {
+	Username             string
+	Password             string
+	IncorrectCredentials bool
+	Errors               FormErrors
+}
+
+// Validate validates the login forms
+func (f *LoginForm) Validate() bool {
+	f.Errors = FormErrors{}
+	f.Username = strings.TrimSpace(f.Username)
+
+	f.Errors.Required("Username", f.Username, "Please enter a username.")
+	if f.IncorrectCredentials {
+		f.Errors.add("Username", "Username or password is incorrect.")
 	}
+	f.Errors.Required("Password", f.Password, "Please enter a password.")
 
 	return len(f.Errors) == 0
 }
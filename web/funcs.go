@@ -0,0 +1,55 @@
+package web
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/nahuakang/goreddit"
+)
+
+// funcMap contains the functions available to all templates
+var funcMap = template.FuncMap{
+	"ago":       ago,
+	"highlight": highlight,
+}
+
+// ago formats t relative to now, e.g. "3 hours ago"
+func ago(t time.Time) string {
+	d := time.Since(t)
+
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute") + " ago"
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour") + " ago"
+	case d < 30*24*time.Hour:
+		return plural(int(d/(24*time.Hour)), "day") + " ago"
+	case d < 365*24*time.Hour:
+		return plural(int(d/(30*24*time.Hour)), "month") + " ago"
+	default:
+		return plural(int(d/(365*24*time.Hour)), "year") + " ago"
+	}
+}
+
+// plural formats n with the unit, adding an s if n is not 1
+func plural(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// highlightReplacer marks the highlighted words of an escaped search result
+var highlightReplacer = strings.NewReplacer(
+	goreddit.HighlightStart, "<mark>",
+	goreddit.HighlightStop, "</mark>",
+)
+
+// highlight escapes s and marks the words matching the search query
+func highlight(s string) template.HTML {
+	return template.HTML(highlightReplacer.Replace(template.HTMLEscapeString(s)))
+}
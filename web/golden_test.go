@@ -0,0 +1,69 @@
+package web
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// normalize replaces the parts of a rendered page that change between runs
+func normalize(body string) string {
+	return csrfPattern.ReplaceAllString(body, `name="gorilla.csrf.Token" value="CSRF_TOKEN"`)
+}
+
+// TestGolden compares the rendered pages with the golden files in testdata.
+// Run go test ./web -update to accept changes to the templates.
+func TestGolden(t *testing.T) {
+	thread := "/threads/" + threadID.String()
+	post := thread + "/" + postID.String()
+
+	tests := []struct {
+		name string
+		user string
+		path string
+	}{
+		{"home", "", "/"},
+		{"home_logged_in", "alice", "/?sort=top&t=all"},
+		{"threads", "", "/threads"},
+		{"thread", "alice", thread},
+		{"thread_admin", "carol", thread},
+		{"thread_create", "alice", "/threads/new"},
+		{"thread_edit", "alice", thread + "/edit"},
+		{"post", "bob", post},
+		{"post_comment", "", post + "/comments/" + replyID.String()},
+		{"post_edit", "alice", post + "/edit"},
+		{"comment_edit", "alice", "/comments/" + commentID.String() + "/edit"},
+		{"search", "", "/search?q=generics"},
+		{"register", "", "/register"},
+		{"login", "", "/login"},
+		{"not_found", "", "/threads/nope"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, newTestStore(t))
+			if tt.user != "" {
+				c.login(tt.user)
+			}
+
+			got := normalize(c.get(tt.path).body)
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("GET %s does not match %s:\n%s", tt.path, golden, got)
+			}
+		})
+	}
+}
@@ -1,78 +1,511 @@
 package web
 
 import (
+	"context"
+	"encoding/json"
 	"html/template"
+	"io/fs"
 	"net/http"
+	"strings"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
+	"github.com/google/uuid"
 	"github.com/gorilla/csrf"
 	"github.com/nahuakang/goreddit"
 )
 
-// NewHandler constructs a new Handler pointer
-func NewHandler(store goreddit.Store, sessions *scs.SessionManager, csrfKey []byte) *Handler {
+// Options configure the Handler built by NewHandler
+type Options struct {
+	// CSRFKey is the 32-byte key authenticating the CSRF tokens
+	CSRFKey []byte
+	// SecureCookies sends the CSRF cookie over HTTPS only
+	SecureCookies bool
+	// Templates contains the HTML templates, which are the ones embedded in
+	// the binary if it is nil
+	Templates fs.FS
+	// Dev parses the templates again for every page, to see edits live
+	Dev bool
+}
+
+// NewHandler constructs a new Handler pointer, panicking if the templates
+// are invalid
+func NewHandler(store goreddit.Store, sessions *scs.SessionManager, opts Options) *Handler {
+	templates, err := NewTemplates(opts.Templates, opts.Dev)
+	if err != nil {
+		panic(err)
+	}
+
+	errs := NewErrorHandler(sessions, templates)
 	h := &Handler{
 		Mux:      chi.NewMux(),
 		store:    store,
 		sessions: sessions,
+		errs:     errs,
 	}
 
-	threads := ThreadHandler{store: store, sessions: sessions}
-	posts := PostHandler{store: store, sessions: sessions}
-	comments := CommentHandler{store: store, sessions: sessions}
+	threads := ThreadHandler{store: store, sessions: sessions, errs: errs}
+	posts := PostHandler{store: store, sessions: sessions, errs: errs}
+	comments := CommentHandler{store: store, sessions: sessions, errs: errs}
+	users := UserHandler{store: store, sessions: sessions, errs: errs}
+	search := SearchHandler{store: store, sessions: sessions, errs: errs}
+	moderators := ModeratorHandler{store: store, sessions: sessions, errs: errs}
+	api := APIHandler{store: store, sessions: sessions}
 
 	h.Use(middleware.Logger)
-	// Set csrf.Secure to false to work on http along https
-	h.Use(csrf.Protect(csrfKey, csrf.Secure(false)))
+	// The API authenticates without cookies and needs no CSRF protection
+	h.Use(skipAPICSRF)
+	// Secure cookies are not sent over plain http, so they are optional
+	h.Use(csrf.Protect(opts.CSRFKey, csrf.Secure(opts.SecureCookies)))
+	// Make the CSRF field available to the forms of the layout
+	h.Use(withCSRFField)
 	// Use SessionManager for middleware
 	h.Use(sessions.LoadAndSave)
+	// Load the logged in user into the request context
+	h.Use(h.withUser)
 
 	h.Get("/", h.Home())
+	// Routes of a thread check the role of the user in it with h.require, or
+	// load it with h.withRole for handlers that also let authors through.
+	// Edit and delete routes refuse authors who were banned from the thread.
 	h.Route("/threads", func(r chi.Router) {
+		thread := threadParam("id")
+		postThread := threadParam("threadID")
+
 		r.Get("/", threads.List())
-		r.Get("/new", threads.Create())
-		r.Post("/", threads.Store())
-		r.Get("/{id}", threads.Show())
-		r.Post("/{id}/delete", threads.Delete())
-		r.Get("/{id}/new", posts.Create())
-		r.Post("/{id}", posts.Store())
-		r.Get("/{threadID}/{postID}", posts.Show())
-		r.Get("/{threadID}/{postID}/vote", posts.Vote())
-		r.Post("/{threadID}/{postID}", comments.Store())
+		r.With(h.require(goreddit.PermCreateThread, noThread)).Get("/new", threads.Create())
+		r.With(h.require(goreddit.PermCreateThread, noThread)).Post("/", threads.Store())
+		r.With(h.withRole(thread)).Get("/{id}", threads.Show())
+		r.With(h.refuseBanned(thread)).Get("/{id}/edit", threads.Edit())
+		r.With(h.refuseBanned(thread)).Post("/{id}/edit", threads.Update())
+		r.With(h.refuseBanned(thread)).Post("/{id}/delete", threads.Delete())
+		r.With(h.require(goreddit.PermManageModerators, thread)).Post("/{id}/moderators", moderators.Add())
+		r.With(h.require(goreddit.PermManageModerators, thread)).Post("/{id}/moderators/{userID}/delete", moderators.Remove())
+		r.With(h.require(goreddit.PermBanUser, thread)).Post("/{id}/bans", moderators.Ban())
+		r.With(h.require(goreddit.PermBanUser, thread)).Post("/{id}/bans/{userID}/delete", moderators.Unban())
+		r.With(h.require(goreddit.PermPost, thread)).Get("/{id}/new", posts.Create())
+		r.With(h.require(goreddit.PermPost, thread)).Post("/{id}", posts.Store())
+		r.With(h.withRole(postThread)).Get("/{threadID}/{postID}", posts.Show())
+		r.With(h.refuseBanned(postThread)).Get("/{threadID}/{postID}/edit", posts.Edit())
+		r.With(h.refuseBanned(postThread)).Post("/{threadID}/{postID}/edit", posts.Update())
+		r.With(h.refuseBanned(postThread)).Post("/{threadID}/{postID}/delete", posts.Delete())
+		r.With(h.withRole(postThread)).Get("/{threadID}/{postID}/comments/{commentID}", posts.Show())
+		r.With(h.require(goreddit.PermPost, postThread)).Post("/{threadID}/{postID}/vote", posts.Vote())
+		r.With(h.require(goreddit.PermPost, postThread)).Post("/{threadID}/{postID}", comments.Store())
 	})
-	h.Get("/comments/{id}/vote", comments.Vote())
+	h.With(h.require(goreddit.PermPost, h.commentThread)).Post("/comments/{id}/vote", comments.Vote())
+	h.With(h.refuseBanned(h.commentThread)).Get("/comments/{id}/edit", comments.Edit())
+	h.With(h.refuseBanned(h.commentThread)).Post("/comments/{id}/edit", comments.Update())
+	h.With(h.refuseBanned(h.commentThread)).Post("/comments/{id}/delete", comments.Delete())
+
+	h.Get("/search", search.Search())
+
+	h.Mount("/api/v1", api.Routes())
+
+	h.Get("/register", users.Register())
+	h.Post("/register", users.RegisterSubmit())
+	h.Get("/login", users.Login())
+	h.Post("/login", users.LoginSubmit())
+	h.Post("/logout", users.Logout())
+	h.NotFound(errs.NotFound)
 
 	return h
 }
 
+// contextKey is the type for values stored in the request context by Handler
+type contextKey string
+
+// Keys of the values stored in the request context
+const (
+	// userContextKey stores the logged in goreddit.User
+	userContextKey contextKey = "user"
+	// roleContextKey stores the goreddit.Role of the logged in user in the
+	// thread of the request
+	roleContextKey contextKey = "role"
+	// csrfFieldContextKey stores the hidden CSRF field of the request
+	csrfFieldContextKey contextKey = "csrf_field"
+)
+
 // Handler with pointer to chi.Mux and our goreddit.Store interface wrapper
 type Handler struct {
 	*chi.Mux
 
 	store    goreddit.Store
 	sessions *scs.SessionManager
+	errs     *ErrorHandler
 }
 
 // Home leads to the homepage
 func (h *Handler) Home() http.HandlerFunc {
 	type data struct {
 		SessionData
-		Posts []goreddit.Post
+		CSRF       template.HTML
+		Sort       goreddit.PostSort
+		Posts      []goreddit.Post
+		Votes      map[uuid.UUID]int
+		Pagination pagination
 	}
 
-	tmpl := template.Must(template.ParseFiles("templates/layout.html", "templates/home.html"))
 	return func(w http.ResponseWriter, r *http.Request) {
-		pp, err := h.store.Posts()
+		sort := postSortFromQuery(r)
+
+		pp, info, err := h.store.Posts(r.Context(), sort, pageFromQuery(r))
+		if err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		votes, err := userPostVotes(r.Context(), h.store, pp)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.errs.Error(w, r, err)
 			return
 		}
 
-		tmpl.Execute(w, data{
+		h.errs.Render(w, r, "home.html", data{
 			SessionData: GetSessionData(r.Context(), h.sessions),
+			CSRF:        csrf.TemplateField(r),
+			Sort:        sort,
 			Posts:       pp,
+			Votes:       votes,
+			Pagination:  newPagination(r, info),
+		})
+	}
+}
+
+// withUser loads the user stored in the session into the request context
+func (h *Handler) withUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := h.sessions.Get(r.Context(), "user_id").(uuid.UUID)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, err := h.store.User(r.Context(), id)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userContextKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// withCSRFField stores the hidden CSRF field of the request in its context,
+// where GetSessionData finds it
+func withCSRFField(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), csrfFieldContextKey, csrf.TemplateField(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireUser redirects to the login page if no user is logged in. JSON
+// requests get a 401 status instead.
+func (h *Handler) requireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := userFromContext(r.Context()); !ok {
+			if wantsJSON(r) {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Please log in first."})
+				return
+			}
+
+			h.sessions.Put(r.Context(), "flash", "Please log in first.")
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// threadFunc returns the id of the thread a request is about
+type threadFunc func(r *http.Request) (uuid.UUID, error)
+
+// threadParam returns a threadFunc reading the thread id from the URL
+// parameter key. Invalid ids cannot exist, so they are not found.
+func threadParam(key string) threadFunc {
+	return func(r *http.Request) (uuid.UUID, error) {
+		id, err := uuid.Parse(chi.URLParam(r, key))
+		if err != nil {
+			return uuid.Nil, goreddit.ErrNotFound
+		}
+		return id, nil
+	}
+}
+
+// noThread is the threadFunc of requests outside of any thread
+func noThread(r *http.Request) (uuid.UUID, error) {
+	return uuid.Nil, nil
+}
+
+// commentThread is the threadFunc of the comment of the {id} URL parameter
+func (h *Handler) commentThread(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return uuid.Nil, goreddit.ErrNotFound
+	}
+
+	c, err := h.store.Comment(r.Context(), id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	p, err := h.store.Post(r.Context(), c.PostID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return p.ThreadID, nil
+}
+
+// withRole loads the role of the logged in user in the thread of the request
+// into the request context
+func (h *Handler) withRole(thread threadFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			threadID, err := thread(r)
+			if err != nil {
+				h.errs.Error(w, r, err)
+				return
+			}
+
+			role, err := userRole(r.Context(), h.store, threadID)
+			if err != nil {
+				h.errs.Error(w, r, err)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), roleContextKey, role)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// require only lets users through whose role in the thread of the request
+// has the permission. Others are sent to the login page if no user is
+// logged in, and get a 403 status otherwise.
+func (h *Handler) require(perm goreddit.Permission, thread threadFunc) func(http.Handler) http.Handler {
+	check := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := roleFromContext(r.Context())
+			if !role.Can(perm) {
+				msg := "You are not allowed to do this."
+				if role == goreddit.RoleBanned {
+					msg = "You are banned from this thread."
+				}
+				forbidden(w, r, msg)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	return func(next http.Handler) http.Handler {
+		return h.requireUser(h.withRole(thread)(check(next)))
+	}
+}
+
+// refuseBanned lets logged in users through unless they were banned from the
+// thread of the request. The handlers check that the user may change the
+// content.
+func (h *Handler) refuseBanned(thread threadFunc) func(http.Handler) http.Handler {
+	check := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if roleFromContext(r.Context()) == goreddit.RoleBanned {
+				forbidden(w, r, "You are banned from this thread.")
+				return
+			}
+
+			next.ServeHTTP(w, r)
 		})
 	}
+
+	return func(next http.Handler) http.Handler {
+		return h.requireUser(h.withRole(thread)(check(next)))
+	}
+}
+
+// forbidden writes a 403 response with the message, as JSON if the client
+// asked for it
+func forbidden(w http.ResponseWriter, r *http.Request, msg string) {
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": msg})
+		return
+	}
+	http.Error(w, msg, http.StatusForbidden)
+}
+
+// userRole gets the role of the logged in user in the thread, which is
+// RoleGuest if no user is logged in
+func userRole(ctx context.Context, store goreddit.Store, threadID uuid.UUID) (goreddit.Role, error) {
+	user, ok := userFromContext(ctx)
+	if !ok {
+		return goreddit.RoleGuest, nil
+	}
+	return store.Role(ctx, user.ID, threadID)
+}
+
+// roleFromContext returns the role stored in the request context. Routes
+// without a thread give the role of the logged in user outside of any thread.
+func roleFromContext(ctx context.Context) goreddit.Role {
+	if role, ok := ctx.Value(roleContextKey).(goreddit.Role); ok {
+		return role
+	}
+
+	user, ok := userFromContext(ctx)
+	switch {
+	case !ok:
+		return goreddit.RoleGuest
+	case user.Admin:
+		return goreddit.RoleAdmin
+	}
+	return goreddit.RoleUser
+}
+
+// userFromContext returns the logged in user stored in the request context
+func userFromContext(ctx context.Context) (goreddit.User, bool) {
+	user, ok := ctx.Value(userContextKey).(goreddit.User)
+	return user, ok
+}
+
+// canModify reports whether the logged in user may edit and delete content
+// written by the user with authorID
+func canModify(ctx context.Context, authorID uuid.NullUUID) bool {
+	user, ok := userFromContext(ctx)
+	return ok && isAuthor(user, authorID)
+}
+
+// canChange reports whether the logged in user may edit or delete content
+// written by the user with authorID, which authors may do unless they were
+// banned from the thread of the request, and users with the permission in it
+func canChange(ctx context.Context, authorID uuid.NullUUID, perm goreddit.Permission) bool {
+	role := roleFromContext(ctx)
+	return (canModify(ctx, authorID) && role != goreddit.RoleBanned) || role.Can(perm)
+}
+
+// isAuthor reports whether user is the user with authorID
+func isAuthor(user goreddit.User, authorID uuid.NullUUID) bool {
+	return authorID.Valid && authorID.UUID == user.ID
+}
+
+// userPostVotes gets the votes of the logged in user on the given posts
+func userPostVotes(ctx context.Context, store goreddit.Store, pp []goreddit.Post) (map[uuid.UUID]int, error) {
+	user, ok := userFromContext(ctx)
+	if !ok || len(pp) == 0 {
+		return map[uuid.UUID]int{}, nil
+	}
+
+	ids := make([]uuid.UUID, len(pp))
+	for i, p := range pp {
+		ids[i] = p.ID
+	}
+	return store.PostVotes(ctx, user.ID, ids)
+}
+
+// userCommentVotes gets the votes of the logged in user on the given comments
+func userCommentVotes(ctx context.Context, store goreddit.Store, cc []goreddit.Comment) (map[uuid.UUID]int, error) {
+	user, ok := userFromContext(ctx)
+	if !ok || len(cc) == 0 {
+		return map[uuid.UUID]int{}, nil
+	}
+
+	ids := make([]uuid.UUID, len(cc))
+	for i, c := range cc {
+		ids[i] = c.ID
+	}
+	return store.CommentVotes(ctx, user.ID, ids)
+}
+
+// voteResponse is the JSON response of the vote handlers
+type voteResponse struct {
+	Votes int `json:"votes"`
+	Vote  int `json:"vote"`
+}
+
+// voteValue returns the new vote value for a vote in direction dir given the
+// current vote of the user. Voting in the same direction twice removes the vote.
+func voteValue(dir string, current int) (int, bool) {
+	var value int
+	switch dir {
+	case "up":
+		value = 1
+	case "down":
+		value = -1
+	default:
+		return 0, false
+	}
+
+	if value == current {
+		return 0, true
+	}
+	return value, true
+}
+
+// wantsJSON reports whether the client asked for a JSON response
+func wantsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// writeJSON encodes v as the JSON response body with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// postSortFromQuery reads the sort order of a post listing from the ?sort=
+// and ?t= query parameters, defaulting to hot posts of the past day
+func postSortFromQuery(r *http.Request) goreddit.PostSort {
+	sort := goreddit.PostSort{Sort: goreddit.SortHot, Period: goreddit.PeriodDay}
+
+	switch s := goreddit.Sort(r.URL.Query().Get("sort")); s {
+	case goreddit.SortHot, goreddit.SortNew, goreddit.SortTop, goreddit.SortControversial:
+		sort.Sort = s
+	}
+
+	switch p := goreddit.Period(r.URL.Query().Get("t")); p {
+	case goreddit.PeriodHour, goreddit.PeriodDay, goreddit.PeriodWeek,
+		goreddit.PeriodMonth, goreddit.PeriodYear, goreddit.PeriodAll:
+		sort.Period = p
+	}
+
+	return sort
+}
+
+// pagination contains the links to the pages next to a listing page
+type pagination struct {
+	Next string
+	Prev string
+}
+
+// newPagination builds the links to the pages of info relative to the
+// current URL, keeping its other query parameters
+func newPagination(r *http.Request, info goreddit.PageInfo) pagination {
+	link := func(key, cursor string) string {
+		if cursor == "" {
+			return ""
+		}
+		q := r.URL.Query()
+		q.Del("after")
+		q.Del("before")
+		q.Set(key, cursor)
+		return "?" + q.Encode()
+	}
+
+	return pagination{
+		Next: link("after", info.Next),
+		Prev: link("before", info.Prev),
+	}
+}
+
+// pageFromQuery reads the page of a listing from the ?after= and ?before=
+// query parameters
+func pageFromQuery(r *http.Request) goreddit.Page {
+	return goreddit.Page{
+		After:  r.URL.Query().Get("after"),
+		Before: r.URL.Query().Get("before"),
+		Limit:  goreddit.DefaultPageLimit,
+	}
 }
@@ -0,0 +1,699 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	sessionstore "github.com/alexedwards/scs/v2/memstore"
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+	"github.com/nahuakang/goreddit/memstore"
+	"github.com/nahuakang/goreddit/templates"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+// The content every test starts with. Alice wrote the thread, the post and
+// the comment, which makes her a moderator of the thread, and Bob replied to
+// the comment. Carol is a site admin and Dave is banned from the thread,
+// after he wrote a post and a comment in it.
+var (
+	aliceID       = uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	bobID         = uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+	carolID       = uuid.MustParse("00000000-0000-0000-0000-00000000000c")
+	daveID        = uuid.MustParse("00000000-0000-0000-0000-00000000000d")
+	threadID      = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	postID        = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	commentID     = uuid.MustParse("00000000-0000-0000-0000-000000000003")
+	replyID       = uuid.MustParse("00000000-0000-0000-0000-000000000004")
+	davePostID    = uuid.MustParse("00000000-0000-0000-0000-000000000005")
+	daveCommentID = uuid.MustParse("00000000-0000-0000-0000-000000000006")
+)
+
+// testPassword is the password of all test users
+const testPassword = "password1"
+
+// newTestStore returns a store with the test content
+func newTestStore(t *testing.T) *memstore.Store {
+	t.Helper()
+
+	password, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	s := memstore.NewStore()
+	steps := []error{
+		s.CreateUser(ctx, &goreddit.User{ID: aliceID, Username: "alice", Password: string(password)}),
+		s.CreateUser(ctx, &goreddit.User{ID: bobID, Username: "bob", Password: string(password)}),
+		s.CreateUser(ctx, &goreddit.User{ID: carolID, Username: "carol", Password: string(password), Admin: true}),
+		s.CreateUser(ctx, &goreddit.User{ID: daveID, Username: "dave", Password: string(password)}),
+		s.CreateThread(ctx, &goreddit.Thread{ID: threadID, UserID: valid(aliceID), Title: "Go", Description: "All things Go"}),
+		s.CreatePost(ctx, &goreddit.Post{ID: postID, ThreadID: threadID, UserID: valid(aliceID), Title: "Generics", Content: "Finally here", Votes: 3}),
+		s.CreateComment(ctx, &goreddit.Comment{ID: commentID, PostID: postID, UserID: valid(aliceID), Content: "Great news", Votes: 2}),
+		s.CreateComment(ctx, &goreddit.Comment{ID: replyID, PostID: postID, ParentID: valid(commentID), UserID: valid(bobID), Content: "Agreed"}),
+		s.CreatePost(ctx, &goreddit.Post{ID: davePostID, ThreadID: threadID, UserID: valid(daveID), Title: "Errors", Content: "Wrapped at last"}),
+		s.CreateComment(ctx, &goreddit.Comment{ID: daveCommentID, PostID: postID, UserID: valid(daveID), Content: "Not for me"}),
+		s.BanUser(ctx, threadID, daveID),
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// valid returns id as a valid uuid.NullUUID
+func valid(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: true}
+}
+
+// testClient is a browser with cookies for a test server. It does not follow
+// redirects, so that they can be checked.
+type testClient struct {
+	t      *testing.T
+	server *httptest.Server
+	client *http.Client
+	csrf   string
+}
+
+// response is the part of an HTTP response that tests check
+type response struct {
+	status   int
+	location string
+	body     string
+}
+
+// csrfPattern finds the CSRF token in a rendered form
+var csrfPattern = regexp.MustCompile(`name="gorilla.csrf.Token" value="([^"]+)"`)
+
+// newTestClient starts a server for the store and gets a CSRF token
+func newTestClient(t *testing.T, store goreddit.Store) *testClient {
+	t.Helper()
+	return newTestClientWithSessions(t, store, NewMemorySessionManager())
+}
+
+// newTestClientWithSessions is newTestClient with the session manager
+func newTestClientWithSessions(t *testing.T, store goreddit.Store, sessions *scs.SessionManager) *testClient {
+	t.Helper()
+
+	server := httptest.NewServer(NewHandler(store, sessions, Options{
+		CSRFKey: []byte("01234567890123456789012345678901"),
+	}))
+	t.Cleanup(server.Close)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &testClient{
+		t:      t,
+		server: server,
+		client: &http.Client{
+			Jar: jar,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+
+	// Any form will do, the token is valid for the whole session
+	m := csrfPattern.FindStringSubmatch(c.get("/register").body)
+	if m == nil {
+		t.Fatal("no CSRF token on the register page")
+	}
+	c.csrf = m[1]
+	return c
+}
+
+// do sends the request and reads the response
+func (c *testClient) do(req *http.Request) response {
+	c.t.Helper()
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return response{status: res.StatusCode, location: res.Header.Get("Location"), body: string(body)}
+}
+
+// get requests the page at path
+func (c *testClient) get(path string) response {
+	c.t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, c.server.URL+path, nil)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return c.do(req)
+}
+
+// post submits the form to path with the CSRF token, coming from the page at
+// referer. Headers are added to the request.
+func (c *testClient) post(path, referer string, form url.Values, header http.Header) response {
+	c.t.Helper()
+
+	values := url.Values{"gorilla.csrf.Token": {c.csrf}}
+	for k, v := range form {
+		values[k] = v
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.server.URL+path, strings.NewReader(values.Encode()))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if referer != "" {
+		req.Header.Set("Referer", c.server.URL+referer)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return c.do(req)
+}
+
+// login logs in as the user
+func (c *testClient) login(username string) {
+	c.t.Helper()
+
+	res := c.post("/login", "/login", url.Values{"username": {username}, "password": {testPassword}}, nil)
+	if res.status != http.StatusFound || res.location != "/" {
+		c.t.Fatalf("logging in as %s: got status %d to %q", username, res.status, res.location)
+	}
+	// Drop the flash message of the login
+	c.get("/login")
+}
+
+// path returns the path of a location, which may be an absolute URL
+func (c *testClient) path(location string) string {
+	return strings.TrimPrefix(location, c.server.URL)
+}
+
+func TestRoutes(t *testing.T) {
+	thread := "/threads/" + threadID.String()
+	post := thread + "/" + postID.String()
+	comment := "/comments/" + commentID.String()
+	reply := "/comments/" + replyID.String()
+	json := http.Header{"Accept": {"application/json"}}
+
+	tests := []struct {
+		name    string
+		user    string
+		method  string
+		path    string
+		referer string
+		form    url.Values
+		header  http.Header
+
+		wantStatus   int
+		wantLocation string
+		// wantBody must be in the response body, or in the page redirected
+		// to, such as a flash message or form error
+		wantBody string
+	}{
+		{name: "home", method: "GET", path: "/", wantStatus: 200, wantBody: "Generics"},
+		{name: "home sorted", method: "GET", path: "/?sort=top&t=week", wantStatus: 200, wantBody: "Generics"},
+		{name: "home invalid cursor", method: "GET", path: "/?sort=top&after=garbage",
+			wantStatus: 302, wantLocation: "/?sort=top", wantBody: "Generics"},
+		{name: "threads invalid cursor", method: "GET", path: "/threads?before=garbage",
+			wantStatus: 302, wantLocation: "/threads", wantBody: "All things Go"},
+		{name: "api invalid cursor", method: "GET", path: "/api/v1/threads?after=garbage",
+			wantStatus: 400, wantBody: `"invalid_cursor"`},
+		{name: "unknown route", method: "GET", path: "/nowhere", wantStatus: 404, wantBody: "Page not found"},
+
+		{name: "threads", method: "GET", path: "/threads", wantStatus: 200, wantBody: "All things Go"},
+		{name: "thread", method: "GET", path: thread, wantStatus: 200, wantBody: "Generics"},
+		{name: "unknown thread", method: "GET", path: "/threads/" + uuid.New().String(), wantStatus: 404, wantBody: "Page not found"},
+		{name: "invalid thread id", method: "GET", path: "/threads/nope", wantStatus: 404, wantBody: "Page not found"},
+		{name: "new thread logged out", method: "GET", path: "/threads/new",
+			wantStatus: 302, wantLocation: "/login", wantBody: "Please log in first."},
+		{name: "new thread", user: "bob", method: "GET", path: "/threads/new", wantStatus: 200, wantBody: "Create a new thread"},
+		{name: "create thread", user: "bob", method: "POST", path: "/threads", referer: "/threads/new",
+			form:       url.Values{"title": {"Rust"}, "description": {"All things Rust"}},
+			wantStatus: 302, wantLocation: "/threads", wantBody: "Your new thread has been created."},
+		{name: "create invalid thread", user: "bob", method: "POST", path: "/threads", referer: "/threads/new",
+			form:       url.Values{"title": {" "}, "description": {"All things Rust"}},
+			wantStatus: 302, wantLocation: "/threads/new", wantBody: "Please enter a title."},
+		{name: "create thread logged out", method: "POST", path: "/threads",
+			form:       url.Values{"title": {"Rust"}, "description": {"All things Rust"}},
+			wantStatus: 302, wantLocation: "/login", wantBody: "Please log in first."},
+		{name: "edit thread", user: "alice", method: "GET", path: thread + "/edit", wantStatus: 200, wantBody: "All things Go"},
+		{name: "edit thread of other user", user: "bob", method: "GET", path: thread + "/edit", wantStatus: 403},
+		{name: "update thread", user: "alice", method: "POST", path: thread + "/edit",
+			form:       url.Values{"title": {"Golang"}, "description": {"All things Go"}},
+			wantStatus: 302, wantLocation: thread, wantBody: "Your thread has been updated."},
+		{name: "update thread too long", user: "alice", method: "POST", path: thread + "/edit",
+			form:       url.Values{"title": {strings.Repeat("Go", 51)}, "description": {"All things Go"}},
+			wantStatus: 302, wantLocation: thread + "/edit", wantBody: "This must be at most 100 characters long."},
+		{name: "delete thread", user: "alice", method: "POST", path: thread + "/delete",
+			wantStatus: 302, wantLocation: "/threads", wantBody: "The thread has been deleted."},
+		{name: "delete thread as admin", user: "carol", method: "POST", path: thread + "/delete",
+			wantStatus: 302, wantLocation: "/threads", wantBody: "The thread has been deleted."},
+		{name: "delete thread of other user", user: "bob", method: "POST", path: thread + "/delete", wantStatus: 403},
+		{name: "delete thread logged out", method: "POST", path: thread + "/delete",
+			wantStatus: 302, wantLocation: "/login", wantBody: "Please log in first."},
+
+		{name: "thread as moderator", user: "alice", method: "GET", path: thread, wantStatus: 200, wantBody: "Add moderator"},
+		{name: "thread as banned user", user: "dave", method: "GET", path: thread, wantStatus: 200,
+			wantBody: "You are banned from this thread."},
+		{name: "add moderator", user: "alice", method: "POST", path: thread + "/moderators",
+			form:       url.Values{"username": {"bob"}},
+			wantStatus: 302, wantLocation: thread, wantBody: "bob is now a moderator of this thread."},
+		{name: "add moderator twice", user: "alice", method: "POST", path: thread + "/moderators",
+			form:       url.Values{"username": {"alice"}},
+			wantStatus: 302, wantLocation: thread, wantBody: "alice already moderates this thread."},
+		{name: "add unknown moderator", user: "alice", method: "POST", path: thread + "/moderators",
+			form:       url.Values{"username": {"zed"}},
+			wantStatus: 302, wantLocation: thread, wantBody: "There is no user called zed."},
+		{name: "add moderator as user", user: "bob", method: "POST", path: thread + "/moderators",
+			form: url.Values{"username": {"bob"}}, wantStatus: 403},
+		{name: "remove moderator as admin", user: "carol", method: "POST", path: thread + "/moderators/" + aliceID.String() + "/delete",
+			wantStatus: 302, wantLocation: thread, wantBody: "alice no longer moderates this thread."},
+		{name: "step down as moderator", user: "alice", method: "POST", path: thread + "/moderators/" + aliceID.String() + "/delete",
+			wantStatus: 302, wantLocation: thread, wantBody: "alice no longer moderates this thread."},
+		{name: "remove other moderator", user: "alice", method: "POST", path: thread + "/moderators/" + carolID.String() + "/delete",
+			wantStatus: 403},
+		{name: "ban user", user: "alice", method: "POST", path: thread + "/bans",
+			form:       url.Values{"username": {"bob"}},
+			wantStatus: 302, wantLocation: thread, wantBody: "bob has been banned from this thread."},
+		{name: "ban admin", user: "alice", method: "POST", path: thread + "/bans",
+			form: url.Values{"username": {"carol"}}, wantStatus: 403},
+		{name: "ban moderator", user: "alice", method: "POST", path: thread + "/bans",
+			form: url.Values{"username": {"alice"}}, wantStatus: 403},
+		{name: "ban user as user", user: "bob", method: "POST", path: thread + "/bans",
+			form: url.Values{"username": {"dave"}}, wantStatus: 403},
+		{name: "unban user", user: "alice", method: "POST", path: thread + "/bans/" + daveID.String() + "/delete",
+			wantStatus: 302, wantLocation: thread, wantBody: "dave is no longer banned from this thread."},
+
+		{name: "new post", user: "bob", method: "GET", path: thread + "/new", wantStatus: 200, wantBody: "Go"},
+		{name: "create post", user: "bob", method: "POST", path: thread, referer: thread + "/new",
+			form:       url.Values{"title": {"Modules"}, "content": {"Go modules are great"}},
+			wantStatus: 302, wantBody: "Your post has been created."},
+		{name: "new post as banned user", user: "dave", method: "GET", path: thread + "/new", wantStatus: 403},
+		{name: "create post as banned user", user: "dave", method: "POST", path: thread, referer: thread + "/new",
+			form: url.Values{"title": {"Modules"}, "content": {"Go modules are great"}}, wantStatus: 403},
+		{name: "create invalid post", user: "bob", method: "POST", path: thread, referer: thread + "/new",
+			form:       url.Values{"title": {"Modules"}, "content": {"Go modules \u202e"}},
+			wantStatus: 302, wantLocation: thread + "/new", wantBody: "This contains characters that are not allowed."},
+		{name: "post", method: "GET", path: post, wantStatus: 200, wantBody: "Great news"},
+		{name: "unknown post", method: "GET", path: thread + "/" + uuid.New().String(), wantStatus: 404, wantBody: "Page not found"},
+		{name: "post in another thread", method: "GET", path: "/threads/" + uuid.New().String() + "/" + postID.String() + "?sort=new",
+			wantStatus: 301, wantLocation: post + "?sort=new", wantBody: "Great news"},
+		{name: "comment thread in another thread", method: "GET",
+			path:       "/threads/" + uuid.New().String() + "/" + postID.String() + "/comments/" + replyID.String(),
+			wantStatus: 301, wantLocation: post + "/comments/" + replyID.String(), wantBody: "Agreed"},
+		{name: "edit post", user: "alice", method: "GET", path: post + "/edit", wantStatus: 200, wantBody: "Finally here"},
+		{name: "edit post of other user", user: "bob", method: "GET", path: post + "/edit", wantStatus: 403},
+		{name: "edit post as moderator", user: "alice", method: "GET", path: thread + "/" + davePostID.String() + "/edit",
+			wantStatus: 200, wantBody: "Wrapped at last"},
+		{name: "update post as admin", user: "carol", method: "POST", path: post + "/edit",
+			form:       url.Values{"title": {"Generics in Go"}, "content": {"Finally here"}},
+			wantStatus: 302, wantLocation: post, wantBody: "Your post has been updated."},
+		{name: "edit post as banned user", user: "dave", method: "GET", path: thread + "/" + davePostID.String() + "/edit",
+			wantStatus: 403, wantBody: "You are banned from this thread."},
+		{name: "update post as banned user", user: "dave", method: "POST", path: thread + "/" + davePostID.String() + "/edit",
+			form:       url.Values{"title": {"Errors"}, "content": {"Still wrapped"}},
+			wantStatus: 403, wantBody: "You are banned from this thread."},
+		{name: "update post", user: "alice", method: "POST", path: post + "/edit",
+			form:       url.Values{"title": {"Generics in Go"}, "content": {"Finally here"}},
+			wantStatus: 302, wantLocation: post, wantBody: "Your post has been updated."},
+		{name: "delete post", user: "alice", method: "POST", path: post + "/delete",
+			wantStatus: 302, wantLocation: thread, wantBody: "The post has been deleted."},
+		{name: "delete post of other user", user: "bob", method: "POST", path: post + "/delete", wantStatus: 403},
+		{name: "delete post as admin", user: "carol", method: "POST", path: post + "/delete",
+			wantStatus: 302, wantLocation: thread, wantBody: "The post has been deleted."},
+		{name: "delete post as banned user", user: "dave", method: "POST", path: thread + "/" + davePostID.String() + "/delete",
+			wantStatus: 403, wantBody: "You are banned from this thread."},
+		{name: "delete post in another thread", user: "alice", method: "POST",
+			path: "/threads/" + uuid.New().String() + "/" + postID.String() + "/delete", wantStatus: 404},
+		{name: "vote on post", user: "bob", method: "POST", path: post + "/vote", referer: "/",
+			form:       url.Values{"dir": {"up"}},
+			wantStatus: 302, wantLocation: "/"},
+		{name: "vote on post with JSON", user: "bob", method: "POST", path: post + "/vote",
+			form: url.Values{"dir": {"down"}}, header: json,
+			wantStatus: 200, wantBody: `{"votes":2,"vote":-1}`},
+		{name: "vote on post in invalid direction", user: "bob", method: "POST", path: post + "/vote",
+			form: url.Values{"dir": {"sideways"}}, wantStatus: 400},
+		{name: "vote on post logged out", method: "POST", path: post + "/vote",
+			form: url.Values{"dir": {"up"}}, header: json, wantStatus: 401},
+		{name: "vote on post as banned user", user: "dave", method: "POST", path: post + "/vote",
+			form: url.Values{"dir": {"up"}}, header: json, wantStatus: 403, wantBody: "You are banned from this thread."},
+
+		{name: "create comment", user: "bob", method: "POST", path: post, referer: post,
+			form:       url.Values{"content": {"Me too"}},
+			wantStatus: 302, wantLocation: post, wantBody: "Your comment has been submitted."},
+		{name: "create reply", user: "bob", method: "POST", path: post, referer: post,
+			form:       url.Values{"content": {"Me too"}, "parent_id": {commentID.String()}},
+			wantStatus: 302, wantLocation: post, wantBody: "Me too"},
+		{name: "create comment as banned user", user: "dave", method: "POST", path: post, referer: post,
+			form: url.Values{"content": {"Me too"}}, wantStatus: 403},
+		{name: "create comment in another thread", user: "dave", method: "POST",
+			path: "/threads/" + uuid.New().String() + "/" + postID.String(), referer: post,
+			form:       url.Values{"content": {"Me too"}},
+			wantStatus: 404},
+		{name: "create empty comment", user: "bob", method: "POST", path: post, referer: post,
+			form:       url.Values{"content": {""}},
+			wantStatus: 302, wantLocation: post, wantBody: "Please enter a text."},
+		{name: "create reply to unknown comment", user: "bob", method: "POST", path: post, referer: post,
+			form:       url.Values{"content": {"Me too"}, "parent_id": {uuid.New().String()}},
+			wantStatus: 400},
+		{name: "create reply to invalid comment id", user: "bob", method: "POST", path: post, referer: post,
+			form:       url.Values{"content": {"Me too"}, "parent_id": {"nope"}},
+			wantStatus: 400, wantBody: "Invalid parent comment"},
+		{name: "edit comment", user: "alice", method: "GET", path: comment + "/edit", wantStatus: 200, wantBody: "Great news"},
+		{name: "edit comment of other user", user: "bob", method: "GET", path: comment + "/edit", wantStatus: 403},
+		{name: "edit comment as moderator", user: "alice", method: "GET", path: reply + "/edit", wantStatus: 200, wantBody: "Agreed"},
+		{name: "update comment as moderator", user: "alice", method: "POST", path: reply + "/edit",
+			form:       url.Values{"content": {"Agreed, mostly"}},
+			wantStatus: 302, wantLocation: post, wantBody: "Agreed, mostly"},
+		{name: "edit comment as banned user", user: "dave", method: "GET", path: "/comments/" + daveCommentID.String() + "/edit",
+			wantStatus: 403, wantBody: "You are banned from this thread."},
+		{name: "update comment as banned user", user: "dave", method: "POST", path: "/comments/" + daveCommentID.String() + "/edit",
+			form:       url.Values{"content": {"Still not for me"}},
+			wantStatus: 403, wantBody: "You are banned from this thread."},
+		{name: "update comment", user: "alice", method: "POST", path: comment + "/edit",
+			form:       url.Values{"content": {"Really great news"}},
+			wantStatus: 302, wantLocation: post, wantBody: "Your comment has been updated."},
+		{name: "delete comment", user: "bob", method: "POST", path: reply + "/delete",
+			wantStatus: 302, wantLocation: post, wantBody: "The comment has been deleted."},
+		{name: "delete comment as moderator", user: "alice", method: "POST", path: reply + "/delete",
+			wantStatus: 302, wantLocation: post, wantBody: "The comment has been deleted."},
+		{name: "delete comment as banned user", user: "dave", method: "POST", path: "/comments/" + daveCommentID.String() + "/delete",
+			wantStatus: 403, wantBody: "You are banned from this thread."},
+		{name: "delete comment of other user", user: "bob", method: "POST", path: comment + "/delete", wantStatus: 403},
+		{name: "vote on comment as banned user", user: "dave", method: "POST", path: comment + "/vote",
+			form: url.Values{"dir": {"up"}}, wantStatus: 403},
+		{name: "vote on comment with JSON", user: "bob", method: "POST", path: comment + "/vote",
+			form: url.Values{"dir": {"up"}}, header: json,
+			wantStatus: 200, wantBody: `{"votes":3,"vote":1}`},
+		{name: "vote on unknown comment", user: "bob", method: "POST", path: "/comments/" + uuid.New().String() + "/vote",
+			form: url.Values{"dir": {"up"}}, wantStatus: 404},
+
+		{name: "search", method: "GET", path: "/search?q=generics", wantStatus: 200, wantBody: "<mark>Generics</mark>"},
+		{name: "search in invalid thread", method: "GET", path: "/search?q=go&thread=nope", wantStatus: 404, wantBody: "Page not found"},
+
+		{name: "register", method: "POST", path: "/register", referer: "/register",
+			form:       url.Values{"username": {"erin"}, "password": {testPassword}},
+			wantStatus: 302, wantLocation: "/login", wantBody: "Your registration was successful. Please log in."},
+		{name: "register taken username", method: "POST", path: "/register", referer: "/register",
+			form:       url.Values{"username": {"alice"}, "password": {testPassword}},
+			wantStatus: 302, wantLocation: "/register", wantBody: "This username is already taken."},
+		{name: "register short password", method: "POST", path: "/register", referer: "/register",
+			form:       url.Values{"username": {"erin"}, "password": {"short"}},
+			wantStatus: 302, wantLocation: "/register", wantBody: "This must be at least 8 characters long."},
+		{name: "register short password with accents", method: "POST", path: "/register", referer: "/register",
+			form:       url.Values{"username": {"erin"}, "password": {"pässwö1"}},
+			wantStatus: 302, wantLocation: "/register", wantBody: "This must be at least 8 characters long."},
+		{name: "register long username", method: "POST", path: "/register", referer: "/register",
+			form:       url.Values{"username": {strings.Repeat("e", 31)}, "password": {testPassword}},
+			wantStatus: 302, wantLocation: "/register", wantBody: "This must be at most 30 characters long."},
+		{name: "register username with banned characters", method: "POST", path: "/register", referer: "/register",
+			form:       url.Values{"username": {"erin\u202e"}, "password": {testPassword}},
+			wantStatus: 302, wantLocation: "/register", wantBody: "This contains characters that are not allowed."},
+		{name: "login", method: "POST", path: "/login", referer: "/login",
+			form:       url.Values{"username": {"alice"}, "password": {testPassword}},
+			wantStatus: 302, wantLocation: "/", wantBody: "You have been logged in successfully."},
+		{name: "login with spaces around username", method: "POST", path: "/login", referer: "/login",
+			form:       url.Values{"username": {" alice "}, "password": {testPassword}},
+			wantStatus: 302, wantLocation: "/", wantBody: "You have been logged in successfully."},
+		{name: "login with wrong password", method: "POST", path: "/login", referer: "/login",
+			form:       url.Values{"username": {"alice"}, "password": {"wrong password"}},
+			wantStatus: 302, wantLocation: "/login", wantBody: "Username or password is incorrect."},
+		{name: "logout", user: "alice", method: "POST", path: "/logout", referer: "/",
+			wantStatus: 302, wantLocation: "/", wantBody: "You have been logged out successfully."},
+		{name: "logout with GET", user: "alice", method: "GET", path: "/logout", wantStatus: 405},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, newTestStore(t))
+			if tt.user != "" {
+				c.login(tt.user)
+			}
+
+			var res response
+			if tt.method == http.MethodPost {
+				res = c.post(tt.path, tt.referer, tt.form, tt.header)
+			} else {
+				res = c.get(tt.path)
+			}
+
+			if res.status != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", res.status, tt.wantStatus)
+			}
+			if res.status == http.StatusFound || res.status == http.StatusMovedPermanently {
+				location := c.path(res.location)
+				if tt.wantLocation != "" && location != tt.wantLocation {
+					t.Errorf("redirected to %q, want %q", location, tt.wantLocation)
+				}
+				res = c.get(location)
+			}
+			if !strings.Contains(res.body, tt.wantBody) {
+				t.Errorf("body does not contain %q:\n%s", tt.wantBody, res.body)
+			}
+		})
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	store := newTestStore(t)
+	c := newTestClient(t, store)
+	c.login("alice")
+
+	c.csrf = "forged"
+	for _, path := range []string{
+		"/threads",
+		"/threads/" + threadID.String() + "/delete",
+		"/threads/" + threadID.String() + "/" + postID.String() + "/vote",
+		"/comments/" + commentID.String() + "/delete",
+		"/login",
+		"/logout",
+	} {
+		form := url.Values{"title": {"Rust"}, "description": {"All things Rust"}, "dir": {"up"}}
+		if res := c.post(path, "", form, nil); res.status != http.StatusForbidden {
+			t.Errorf("POST %s with forged CSRF token: got status %d, want %d", path, res.status, http.StatusForbidden)
+		}
+	}
+
+	if res := c.get("/"); !strings.Contains(res.body, "Log out") {
+		t.Error("logged out by a forged request")
+	}
+
+	tt, _, err := store.Threads(context.Background(), goreddit.Page{})
+	if err != nil || len(tt) != 1 {
+		t.Errorf("got %d threads and error %v after forged requests, want 1", len(tt), err)
+	}
+	p, err := store.Post(context.Background(), postID)
+	if err != nil || p.Votes != 3 {
+		t.Errorf("got %d votes and error %v after forged requests, want 3", p.Votes, err)
+	}
+}
+
+// failingStore fails to list posts and threads and to get the page of a post
+type failingStore struct {
+	goreddit.Store
+}
+
+// errDatabase is the error of failingStore, which must not be shown to users
+var errDatabase = errors.New("connection to secret-db.internal refused")
+
+func (s failingStore) Posts(ctx context.Context, sort goreddit.PostSort, page goreddit.Page) ([]goreddit.Post, goreddit.PageInfo, error) {
+	return nil, goreddit.PageInfo{}, errDatabase
+}
+
+func (s failingStore) PostView(ctx context.Context, id uuid.UUID, rootID uuid.NullUUID, sort goreddit.Sort, maxDepth int, page goreddit.Page) (goreddit.PostView, error) {
+	return goreddit.PostView{}, errDatabase
+}
+
+func (s failingStore) Threads(ctx context.Context, page goreddit.Page) ([]goreddit.Thread, goreddit.PageInfo, error) {
+	return nil, goreddit.PageInfo{}, errDatabase
+}
+
+func TestStoreError(t *testing.T) {
+	c := newTestClient(t, failingStore{newTestStore(t)})
+
+	for _, path := range []string{"/", "/threads", "/threads/" + threadID.String() + "/" + postID.String()} {
+		res := c.get(path)
+		if res.status != http.StatusInternalServerError {
+			t.Errorf("GET %s: got status %d, want %d", path, res.status, http.StatusInternalServerError)
+		}
+		if strings.Contains(res.body, "secret-db") {
+			t.Errorf("GET %s shows the database error: %s", path, res.body)
+		}
+		if !strings.Contains(res.body, "Something went wrong") {
+			t.Errorf("GET %s does not show the error page: %s", path, res.body)
+		}
+	}
+}
+
+// conflictingStore fails to create threads as if their id was taken
+type conflictingStore struct {
+	goreddit.Store
+}
+
+func (s conflictingStore) CreateThread(ctx context.Context, t *goreddit.Thread) error {
+	return fmt.Errorf("Error creating thread: %w", goreddit.ErrConflict)
+}
+
+func TestConflictError(t *testing.T) {
+	c := newTestClient(t, conflictingStore{newTestStore(t)})
+	c.login("bob")
+
+	form := url.Values{"title": {"Rust"}, "description": {"All things Rust"}}
+	res := c.post("/threads", "/threads/new", form, nil)
+	if res.status != http.StatusConflict {
+		t.Errorf("got status %d, want %d", res.status, http.StatusConflict)
+	}
+	if !strings.Contains(res.body, "Already changed") {
+		t.Errorf("does not show the conflict page: %s", res.body)
+	}
+}
+
+func TestTemplateError(t *testing.T) {
+	// The home page fails halfway through, after writing some of its content
+	fsys := fstest.MapFS{}
+	files, err := fs.ReadDir(templates.FS, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		b, err := fs.ReadFile(templates.FS, f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys[f.Name()] = &fstest.MapFile{Data: b}
+	}
+	fsys["home.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}half-written {{.Posts.Missing}}{{end}}`)}
+
+	var logs strings.Builder
+	log.SetOutput(&logs)
+	defer log.SetOutput(ioutil.Discard)
+
+	h := NewHandler(newTestStore(t), NewMemorySessionManager(), Options{
+		CSRFKey:   []byte("01234567890123456789012345678901"),
+		Templates: fsys,
+	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if body := w.Body.String(); strings.Contains(body, "half-written") || !strings.Contains(body, "Something went wrong") {
+		t.Errorf("got %s, want only the error page", body)
+	}
+	if !strings.Contains(logs.String(), "GET /: Error rendering home.html") {
+		t.Errorf("got logs %q, want the route and the template", logs.String())
+	}
+}
+
+// recordingStore is a session store that keeps a copy of every committed
+// session
+type recordingStore struct {
+	scs.Store
+
+	mu        sync.Mutex
+	committed [][]byte
+}
+
+func (s *recordingStore) Commit(token string, b []byte, expiry time.Time) error {
+	s.mu.Lock()
+	s.committed = append(s.committed, b)
+	s.mu.Unlock()
+	return s.Store.Commit(token, b, expiry)
+}
+
+func TestFormsWithoutPassword(t *testing.T) {
+	const password = "secret password"
+	for _, tt := range []struct {
+		name string
+		path string
+		form url.Values
+	}{
+		{"register taken username", "/register", url.Values{"username": {"alice"}, "password": {password}}},
+		{"register empty username", "/register", url.Values{"username": {""}, "password": {password}}},
+		{"login", "/login", url.Values{"username": {"alice"}, "password": {password}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &recordingStore{Store: sessionstore.New()}
+			sessions := NewMemorySessionManager()
+			sessions.Store = store
+			c := newTestClientWithSessions(t, newTestStore(t), sessions)
+
+			res := c.post(tt.path, tt.path, tt.form, nil)
+			if res.status != http.StatusFound || c.path(res.location) != tt.path {
+				t.Fatalf("got status %d to %q, want the form again", res.status, res.location)
+			}
+			if body := c.get(tt.path).body; strings.Contains(body, password) {
+				t.Errorf("the form shows the password:\n%s", body)
+			}
+
+			store.mu.Lock()
+			defer store.mu.Unlock()
+			for _, b := range store.committed {
+				if bytes.Contains(b, []byte(password)) {
+					t.Fatal("the password was stored in the session")
+				}
+			}
+		})
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	if cost, err := bcrypt.Cost([]byte(dummyPasswordHash)); err != nil || cost != bcrypt.DefaultCost {
+		t.Errorf("dummy hash has cost %d and error %v, want %d", cost, err, bcrypt.DefaultCost)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := goreddit.User{Username: "alice", Password: string(hash)}
+
+	tests := []struct {
+		name     string
+		user     goreddit.User
+		err      error
+		password string
+		want     bool
+	}{
+		{"correct password", user, nil, testPassword, true},
+		{"wrong password", user, nil, "wrong password", false},
+		{"unknown user", goreddit.User{}, goreddit.ErrNotFound, testPassword, false},
+		{"unknown user with empty password", goreddit.User{}, goreddit.ErrNotFound, "", false},
+	}
+	for _, tt := range tests {
+		if got := checkPassword(tt.user, tt.err, tt.password); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
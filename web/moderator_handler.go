@@ -0,0 +1,192 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// ModeratorHandler handles the moderators and banned users of threads
+//
+// Its routes are guarded by the permissions of the role of the logged in
+// user in the thread. Acting on moderators additionally takes a site admin,
+// so that moderators cannot remove or ban each other, but they may step down.
+type ModeratorHandler struct {
+	store    goreddit.Store
+	sessions *scs.SessionManager
+	errs     *ErrorHandler
+}
+
+// Add makes the user with the username of the form a moderator of the thread
+func (h *ModeratorHandler) Add() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, ok := h.thread(w, r)
+		if !ok {
+			return
+		}
+
+		u, ok := h.userByUsername(w, r, t)
+		if !ok {
+			return
+		}
+
+		if err := h.store.AddModerator(r.Context(), t.ID, u.ID); errors.Is(err, goreddit.ErrConflict) {
+			h.sessions.Put(r.Context(), "flash", u.Username+" already moderates this thread.")
+		} else if err != nil {
+			h.errs.Error(w, r, err)
+			return
+		} else {
+			h.sessions.Put(r.Context(), "flash", u.Username+" is now a moderator of this thread.")
+		}
+
+		http.Redirect(w, r, "/threads/"+t.ID.String(), http.StatusFound)
+	}
+}
+
+// Remove removes the user of the URL from the moderators of the thread
+func (h *ModeratorHandler) Remove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, ok := h.thread(w, r)
+		if !ok {
+			return
+		}
+
+		u, ok := h.user(w, r)
+		if !ok {
+			return
+		}
+
+		if user, _ := userFromContext(r.Context()); u.ID != user.ID && roleFromContext(r.Context()) != goreddit.RoleAdmin {
+			http.Error(w, "Only site admins may remove other moderators", http.StatusForbidden)
+			return
+		}
+
+		if err := h.store.RemoveModerator(r.Context(), t.ID, u.ID); err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		h.sessions.Put(r.Context(), "flash", u.Username+" no longer moderates this thread.")
+
+		http.Redirect(w, r, "/threads/"+t.ID.String(), http.StatusFound)
+	}
+}
+
+// Ban bans the user with the username of the form from the thread
+func (h *ModeratorHandler) Ban() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, ok := h.thread(w, r)
+		if !ok {
+			return
+		}
+
+		u, ok := h.userByUsername(w, r, t)
+		if !ok {
+			return
+		}
+
+		role, err := h.store.Role(r.Context(), u.ID, t.ID)
+		if err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+		switch {
+		case role == goreddit.RoleAdmin:
+			http.Error(w, "Site admins cannot be banned", http.StatusForbidden)
+			return
+		case role == goreddit.RoleModerator && roleFromContext(r.Context()) != goreddit.RoleAdmin:
+			http.Error(w, "Only site admins may ban moderators", http.StatusForbidden)
+			return
+		}
+
+		if err := h.store.BanUser(r.Context(), t.ID, u.ID); errors.Is(err, goreddit.ErrConflict) {
+			h.sessions.Put(r.Context(), "flash", u.Username+" is already banned from this thread.")
+		} else if err != nil {
+			h.errs.Error(w, r, err)
+			return
+		} else {
+			h.sessions.Put(r.Context(), "flash", u.Username+" has been banned from this thread.")
+		}
+
+		http.Redirect(w, r, "/threads/"+t.ID.String(), http.StatusFound)
+	}
+}
+
+// Unban lifts the ban of the user of the URL from the thread
+func (h *ModeratorHandler) Unban() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, ok := h.thread(w, r)
+		if !ok {
+			return
+		}
+
+		u, ok := h.user(w, r)
+		if !ok {
+			return
+		}
+
+		if err := h.store.UnbanUser(r.Context(), t.ID, u.ID); err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		h.sessions.Put(r.Context(), "flash", u.Username+" is no longer banned from this thread.")
+
+		http.Redirect(w, r, "/threads/"+t.ID.String(), http.StatusFound)
+	}
+}
+
+// thread gets the thread of the {id} URL parameter, writing an error response
+// if it does not exist
+func (h *ModeratorHandler) thread(w http.ResponseWriter, r *http.Request) (goreddit.Thread, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.errs.NotFound(w, r)
+		return goreddit.Thread{}, false
+	}
+
+	t, err := h.store.Thread(r.Context(), id)
+	if err != nil {
+		h.errs.Error(w, r, err)
+		return goreddit.Thread{}, false
+	}
+	return t, true
+}
+
+// user gets the user of the {userID} URL parameter, writing an error response
+// if it does not exist
+func (h *ModeratorHandler) user(w http.ResponseWriter, r *http.Request) (goreddit.User, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		h.errs.NotFound(w, r)
+		return goreddit.User{}, false
+	}
+
+	u, err := h.store.User(r.Context(), id)
+	if err != nil {
+		h.errs.Error(w, r, err)
+		return goreddit.User{}, false
+	}
+	return u, true
+}
+
+// userByUsername gets the user with the username of the form. If there is
+// none, it redirects back to the thread t with a flash message.
+func (h *ModeratorHandler) userByUsername(w http.ResponseWriter, r *http.Request, t goreddit.Thread) (goreddit.User, bool) {
+	username := r.FormValue("username")
+
+	u, err := h.store.UserByUsername(r.Context(), username)
+	if errors.Is(err, goreddit.ErrNotFound) {
+		h.sessions.Put(r.Context(), "flash", "There is no user called "+username+".")
+		http.Redirect(w, r, "/threads/"+t.ID.String(), http.StatusFound)
+		return goreddit.User{}, false
+	} else if err != nil {
+		h.errs.Error(w, r, err)
+		return goreddit.User{}, false
+	}
+	return u, true
+}
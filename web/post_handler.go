@@ -11,10 +11,15 @@ import (
 	"github.com/nahuakang/goreddit"
 )
 
+// commentMaxDepth is the number of comment levels shown on a post page before
+// linking to the rest of the thread
+const commentMaxDepth = 8
+
 // PostHandler handles posts
 type PostHandler struct {
 	store    goreddit.Store
 	sessions *scs.SessionManager
+	errs     *ErrorHandler
 }
 
 // Create leads to the page for creating new post
@@ -25,21 +30,20 @@ func (h *PostHandler) Create() http.HandlerFunc {
 		Thread goreddit.Thread
 	}
 
-	tmpl := template.Must(template.ParseFiles("templates/layout.html", "templates/post_create.html"))
 	return func(w http.ResponseWriter, r *http.Request) {
 		idStr := chi.URLParam(r, "id")
 		id, err := uuid.Parse(idStr)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.errs.NotFound(w, r)
 			return
 		}
 
-		t, err := h.store.Thread(id)
+		t, err := h.store.Thread(r.Context(), id)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.errs.Error(w, r, err)
 			return
 		}
-		tmpl.Execute(w, data{
+		h.errs.Render(w, r, "post_create.html", data{
 			SessionData: GetSessionData(r.Context(), h.sessions),
 			CSRF:        csrf.TemplateField(r),
 			Thread:      t,
@@ -64,25 +68,28 @@ func (h *PostHandler) Store() http.HandlerFunc {
 
 		id, err := uuid.Parse(idStr)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.errs.NotFound(w, r)
 			return
 		}
 
-		t, err := h.store.Thread(id)
+		t, err := h.store.Thread(r.Context(), id)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.errs.Error(w, r, err)
 			return
 		}
 
+		user, _ := userFromContext(r.Context())
+
 		p := &goreddit.Post{
 			ID:       uuid.New(),
 			ThreadID: t.ID,
+			UserID:   uuid.NullUUID{UUID: user.ID, Valid: true},
 			Title:    form.Title,
 			Content:  form.Content,
 		}
 
-		if err := h.store.CreatePost(p); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if err := h.store.CreatePost(r.Context(), p); err != nil {
+			h.errs.Error(w, r, err)
 			return
 		}
 
@@ -92,53 +99,95 @@ func (h *PostHandler) Store() http.HandlerFunc {
 	}
 }
 
-// Show leads to the page for creating new post
+// Show leads to the page of a post with its comments
 func (h *PostHandler) Show() http.HandlerFunc {
 	type data struct {
 		SessionData
-		CSRF     template.HTML
-		Thread   goreddit.Thread
-		Post     goreddit.Post
-		Comments []goreddit.Comment
+		CSRF         template.HTML
+		Thread       goreddit.Thread
+		Post         goreddit.Post
+		Comments     []goreddit.Comment
+		CommentSort  goreddit.Sort
+		Focused      bool
+		LastDepth    int
+		PostVotes    map[uuid.UUID]int
+		CommentVotes map[uuid.UUID]int
+		Pagination   pagination
 	}
-	tmpl := template.Must(template.ParseFiles("templates/layout.html", "templates/post.html"))
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		postIDStr := chi.URLParam(r, "postID")
 		threadIDStr := chi.URLParam(r, "threadID")
 
 		postID, err := uuid.Parse(postIDStr)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.errs.NotFound(w, r)
 			return
 		}
 
 		threadID, err := uuid.Parse(threadIDStr)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.errs.NotFound(w, r)
 			return
 		}
 
-		p, err := h.store.Post(postID)
+		// Show a single comment thread when following a "continue this thread" link
+		var rootID uuid.NullUUID
+		if commentIDStr := chi.URLParam(r, "commentID"); commentIDStr != "" {
+			commentID, err := uuid.Parse(commentIDStr)
+			if err != nil {
+				h.errs.NotFound(w, r)
+				return
+			}
+			rootID = uuid.NullUUID{UUID: commentID, Valid: true}
+		}
+
+		commentSort := goreddit.SortTop
+		if r.URL.Query().Get("sort") == string(goreddit.SortNew) {
+			commentSort = goreddit.SortNew
+		}
+
+		v, err := h.store.PostView(r.Context(), postID, rootID, commentSort, commentMaxDepth, pageFromQuery(r))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		// Links with another thread lead to the one the post belongs to
+		if v.Post.ThreadID != threadID {
+			u := *r.URL
+			u.Path = "/threads/" + v.Post.ThreadID.String() + "/" + v.Post.ID.String()
+			if rootID.Valid {
+				u.Path += "/comments/" + rootID.UUID.String()
+			}
+			http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+			return
 		}
 
-		cc, err := h.store.CommentsByPost(p.ID)
+		postVotes, err := userPostVotes(r.Context(), h.store, []goreddit.Post{v.Post})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.errs.Error(w, r, err)
+			return
 		}
 
-		t, err := h.store.Thread(threadID)
+		commentVotes, err := userCommentVotes(r.Context(), h.store, v.Comments)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.errs.Error(w, r, err)
+			return
 		}
 
-		tmpl.Execute(w, data{
-			SessionData: GetSessionData(r.Context(), h.sessions),
-			CSRF:        csrf.TemplateField(r),
-			Thread:      t,
-			Post:        p,
-			Comments:    cc,
+		h.errs.Render(w, r, "post.html", data{
+			SessionData:  GetSessionData(r.Context(), h.sessions),
+			CSRF:         csrf.TemplateField(r),
+			Thread:       v.Thread,
+			Post:         v.Post,
+			Comments:     v.Comments,
+			CommentSort:  commentSort,
+			Focused:      rootID.Valid,
+			LastDepth:    commentMaxDepth - 1,
+			PostVotes:    postVotes,
+			CommentVotes: commentVotes,
+			Pagination:   newPagination(r, v.PageInfo),
 		})
 	}
 }
@@ -146,32 +195,166 @@ func (h *PostHandler) Show() http.HandlerFunc {
 // Vote stores information about votes on a post
 func (h *PostHandler) Vote() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		idStr := chi.URLParam(r, "postID")
+		p, ok := h.post(w, r)
+		if !ok {
+			return
+		}
 
-		id, err := uuid.Parse(idStr)
+		user, _ := userFromContext(r.Context())
+
+		votes, err := h.store.PostVotes(r.Context(), user.ID, []uuid.UUID{p.ID})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.errs.Error(w, r, err)
 			return
 		}
 
-		p, err := h.store.Post(id)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		value, ok := voteValue(r.FormValue("dir"), votes[p.ID])
+		if !ok {
+			http.Error(w, "Invalid vote direction", http.StatusBadRequest)
 			return
 		}
 
-		dir := r.URL.Query().Get("dir")
-		if dir == "up" {
-			p.Votes++
-		} else if dir == "down" {
-			p.Votes--
+		if err := h.store.VotePost(r.Context(), user.ID, p.ID, value); err != nil {
+			h.errs.Error(w, r, err)
+			return
 		}
 
-		if err := h.store.UpdatePost(&p); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if wantsJSON(r) {
+			p, err := h.store.Post(r.Context(), p.ID)
+			if err != nil {
+				h.errs.Error(w, r, err)
+				return
+			}
+
+			writeJSON(w, http.StatusOK, voteResponse{Votes: p.Votes, Vote: value})
 			return
 		}
 
 		http.Redirect(w, r, r.Referer(), http.StatusFound)
 	}
 }
+
+// Edit leads to the page for editing a post
+func (h *PostHandler) Edit() http.HandlerFunc {
+	type data struct {
+		SessionData
+		CSRF   template.HTML
+		Thread goreddit.Thread
+		Post   goreddit.Post
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := h.modifiablePost(w, r)
+		if !ok {
+			return
+		}
+
+		t, err := h.store.Thread(r.Context(), p.ThreadID)
+		if err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		h.errs.Render(w, r, "post_edit.html", data{
+			SessionData: GetSessionData(r.Context(), h.sessions),
+			CSRF:        csrf.TemplateField(r),
+			Thread:      t,
+			Post:        p,
+		})
+	}
+}
+
+// Update saves the changes to an edited post to database
+func (h *PostHandler) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := h.modifiablePost(w, r)
+		if !ok {
+			return
+		}
+
+		form := CreatePostForm{
+			Title:   r.FormValue("title"),
+			Content: r.FormValue("content"),
+		}
+		if !form.Validate() {
+			h.sessions.Put(r.Context(), "form", form)
+			http.Redirect(w, r, r.URL.Path, http.StatusFound)
+			return
+		}
+
+		p.Title = form.Title
+		p.Content = form.Content
+
+		if err := h.store.UpdatePost(r.Context(), &p); err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		h.sessions.Put(r.Context(), "flash", "Your post has been updated.")
+
+		http.Redirect(w, r, "/threads/"+p.ThreadID.String()+"/"+p.ID.String(), http.StatusFound)
+	}
+}
+
+// Delete deletes a post, which its author and the moderators of its thread
+// may do
+func (h *PostHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := h.post(w, r)
+		if !ok {
+			return
+		}
+
+		if !canChange(r.Context(), p.UserID, goreddit.PermRemovePost) {
+			http.Error(w, "You may only delete your own posts", http.StatusForbidden)
+			return
+		}
+
+		if err := h.store.DeletePost(r.Context(), p.ID); err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		h.sessions.Put(r.Context(), "flash", "The post has been deleted.")
+
+		http.Redirect(w, r, "/threads/"+p.ThreadID.String(), http.StatusFound)
+	}
+}
+
+// modifiablePost gets the post of the request, writing an error response if
+// it does not exist or the logged in user may not modify it
+func (h *PostHandler) modifiablePost(w http.ResponseWriter, r *http.Request) (goreddit.Post, bool) {
+	p, ok := h.post(w, r)
+	if !ok {
+		return goreddit.Post{}, false
+	}
+
+	if !canChange(r.Context(), p.UserID, goreddit.PermEditPost) {
+		http.Error(w, "You may only change your own posts", http.StatusForbidden)
+		return goreddit.Post{}, false
+	}
+	return p, true
+}
+
+// post gets the post of the request, writing an error response if it does
+// not exist in the thread of the URL
+func (h *PostHandler) post(w http.ResponseWriter, r *http.Request) (goreddit.Post, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "postID"))
+	if err != nil {
+		h.errs.NotFound(w, r)
+		return goreddit.Post{}, false
+	}
+
+	p, err := h.store.Post(r.Context(), id)
+	if err != nil {
+		h.errs.Error(w, r, err)
+		return goreddit.Post{}, false
+	}
+
+	// The role of the user was checked in the thread of the URL
+	if p.ThreadID.String() != chi.URLParam(r, "threadID") {
+		h.errs.NotFound(w, r)
+		return goreddit.Post{}, false
+	}
+	return p, true
+}
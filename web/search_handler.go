@@ -0,0 +1,71 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+)
+
+// SearchHandler handles searching
+type SearchHandler struct {
+	store    goreddit.Store
+	sessions *scs.SessionManager
+	errs     *ErrorHandler
+}
+
+// Search returns a webpage with the threads, posts and comments matching ?q=,
+// optionally restricted to the thread given by ?thread=
+func (h *SearchHandler) Search() http.HandlerFunc {
+	type data struct {
+		SessionData
+		Query      string
+		Thread     *goreddit.Thread
+		Results    []goreddit.SearchResult
+		Pagination pagination
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+		var threadID uuid.NullUUID
+		var thread *goreddit.Thread
+		if threadIDStr := r.URL.Query().Get("thread"); threadIDStr != "" {
+			// Invalid ids cannot exist, so the thread is not found
+			id, err := uuid.Parse(threadIDStr)
+			if err != nil {
+				h.errs.NotFound(w, r)
+				return
+			}
+
+			t, err := h.store.Thread(r.Context(), id)
+			if err != nil {
+				h.errs.Error(w, r, err)
+				return
+			}
+			threadID = uuid.NullUUID{UUID: t.ID, Valid: true}
+			thread = &t
+		}
+
+		var rr []goreddit.SearchResult
+		var info goreddit.PageInfo
+		if query != "" {
+			var err error
+			rr, info, err = h.store.Search(r.Context(), query, threadID, pageFromQuery(r))
+			if err != nil {
+				h.errs.Error(w, r, err)
+				return
+			}
+		}
+
+		h.errs.Render(w, r, "search.html", data{
+			SessionData: GetSessionData(r.Context(), h.sessions),
+			Query:       query,
+			Thread:      thread,
+			Results:     rr,
+			Pagination:  newPagination(r, info),
+		})
+	}
+}
@@ -3,16 +3,45 @@ package web
 import (
 	"context"
 	"database/sql"
+	"encoding/gob"
+	"html/template"
 
 	"github.com/alexedwards/scs/postgresstore"
+	"github.com/alexedwards/scs/sqlite3store"
 	"github.com/alexedwards/scs/v2"
+	"github.com/google/uuid"
+	"github.com/nahuakang/goreddit"
+	"github.com/nahuakang/goreddit/sqlite"
 )
 
-// SessionData contains data for flash messages
+func init() {
+	gob.Register(uuid.UUID{})
+}
+
+// SessionData contains data for flash messages and the logged in user
 type SessionData struct {
 	FlashMessage string
 	Form         interface{} // So that it works with any kind of forms
-	// UserID uuid.UUID3
+	User         goreddit.User
+	LoggedIn     bool
+	// Role is the role of the logged in user in the thread of the page
+	Role goreddit.Role
+	// CSRF is the hidden CSRF field for the forms of the layout, such as the
+	// logout button
+	CSRF template.HTML
+}
+
+// CanModify reports whether the logged in user may edit and delete content
+// written by the user with authorID, which authors banned from the thread of
+// the page may not
+func (d SessionData) CanModify(authorID uuid.NullUUID) bool {
+	return d.LoggedIn && isAuthor(d.User, authorID) && d.Role != goreddit.RoleBanned
+}
+
+// Can reports whether the role of the logged in user has the permission,
+// such as {{if .Can "remove_post"}} in templates
+func (d SessionData) Can(p goreddit.Permission) bool {
+	return d.Role.Can(p)
 }
 
 // NewSessionManager manages sessions for Goreddit
@@ -28,12 +57,34 @@ func NewSessionManager(dataSourceName string) (*scs.SessionManager, error) {
 	return sessions, nil
 }
 
+// NewSQLiteSessionManager manages sessions for Goreddit in the sessions
+// table of the SQLite database file at path
+func NewSQLiteSessionManager(path string) (*scs.SessionManager, error) {
+	db, err := sqlite.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := scs.New()
+	sessions.Store = sqlite3store.New(db.DB)
+
+	return sessions, nil
+}
+
+// NewMemorySessionManager manages sessions for Goreddit in memory, so that
+// they are lost when the server stops
+func NewMemorySessionManager() *scs.SessionManager {
+	return scs.New()
+}
+
 // GetSessionData grabs data from the SessionManager
 func GetSessionData(ctx context.Context, session *scs.SessionManager) SessionData {
 	var data SessionData
 
 	data.FlashMessage = session.PopString(ctx, "flash")
-	// data.UserID, _ = session.Get(ctx, "user_id").(uuid.UUID)
+	data.User, data.LoggedIn = userFromContext(ctx)
+	data.Role = roleFromContext(ctx)
+	data.CSRF, _ = ctx.Value(csrfFieldContextKey).(template.HTML)
 
 	data.Form = session.Pop(ctx, "form")
 	if data.Form == nil {
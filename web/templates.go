@@ -0,0 +1,121 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/nahuakang/goreddit/templates"
+)
+
+// pages lists the templates that each page uses besides layout.html
+var pages = map[string][]string{
+	"404.html":           nil,
+	"409.html":           nil,
+	"500.html":           nil,
+	"comment_edit.html":  nil,
+	"home.html":          {"sort_tabs.html", "pagination.html"},
+	"post.html":          {"pagination.html"},
+	"post_create.html":   nil,
+	"post_edit.html":     nil,
+	"search.html":        {"pagination.html"},
+	"thread.html":        {"sort_tabs.html", "pagination.html"},
+	"thread_create.html": nil,
+	"thread_edit.html":   nil,
+	"threads.html":       {"pagination.html"},
+	"user_login.html":    nil,
+	"user_register.html": nil,
+}
+
+// Templates renders the pages, parsed once with the functions of funcMap.
+// In dev mode the pages are parsed again whenever they are rendered, so that
+// edits to the templates show up without restarting.
+type Templates struct {
+	fsys  fs.FS
+	dev   bool
+	pages map[string]*template.Template
+}
+
+// NewTemplates parses the pages of fsys, or of the templates embedded in the
+// binary if fsys is nil
+func NewTemplates(fsys fs.FS, dev bool) (*Templates, error) {
+	if fsys == nil {
+		fsys = templates.FS
+	}
+
+	t := &Templates{fsys: fsys, dev: dev, pages: map[string]*template.Template{}}
+	for page := range pages {
+		tmpl, err := t.parse(page)
+		if err != nil {
+			return nil, err
+		}
+		t.pages[page] = tmpl
+	}
+	return t, nil
+}
+
+// parse parses layout.html and the templates of the page
+func (t *Templates) parse(page string) (*template.Template, error) {
+	files, ok := pages[page]
+	if !ok {
+		return nil, fmt.Errorf("unknown page %q", page)
+	}
+
+	patterns := append([]string{"layout.html", page}, files...)
+	tmpl, err := template.New("layout.html").Funcs(funcMap).ParseFS(t.fsys, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing templates of %s: %w", page, err)
+	}
+	return tmpl, nil
+}
+
+// Execute renders the page with the data to w
+func (t *Templates) Execute(w io.Writer, page string, data interface{}) error {
+	tmpl, ok := t.pages[page]
+	if t.dev || !ok {
+		var err error
+		if tmpl, err = t.parse(page); err != nil {
+			return err
+		}
+	}
+	return tmpl.Execute(w, data)
+}
+
+// maxPooledBuffer is the capacity above which rendering buffers are dropped
+// rather than pooled, so that a single huge page does not stay in memory
+const maxPooledBuffer = 1 << 20
+
+// buffers pools the buffers that pages are rendered into
+var buffers = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// Render renders the page with the data into a buffer, and writes it as the
+// response with the status only if that succeeds. Otherwise nothing is
+// written and the error is returned. Errors writing the response, such as a
+// client that went away, are only logged, as the status is already sent.
+func (t *Templates) Render(w http.ResponseWriter, status int, page string, data interface{}) error {
+	b := buffers.Get().(*bytes.Buffer)
+	b.Reset()
+	defer func() {
+		if b.Cap() <= maxPooledBuffer {
+			buffers.Put(b)
+		}
+	}()
+
+	if err := t.Execute(b, page, data); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := b.WriteTo(w); err != nil {
+		log.Printf("Error writing %s: %v", page, err)
+	}
+	return nil
+}
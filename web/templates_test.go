@@ -0,0 +1,101 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// testTemplates returns templates whose pages only show their name, with
+// home.html showing the given text
+func testTemplates(home string) fstest.MapFS {
+	fsys := fstest.MapFS{
+		"layout.html": {Data: []byte(`{{template "content" .}}`)},
+	}
+	for page, files := range pages {
+		fsys[page] = &fstest.MapFile{Data: []byte(`{{define "content"}}` + page + `{{end}}`)}
+		for _, f := range files {
+			fsys[f] = &fstest.MapFile{}
+		}
+	}
+	fsys["home.html"] = &fstest.MapFile{Data: []byte(`{{define "content"}}` + home + `{{end}}`)}
+	return fsys
+}
+
+func TestTemplatesEmbedded(t *testing.T) {
+	if _, err := NewTemplates(nil, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTemplatesDev(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		dev  bool
+		want string
+	}{
+		{"production", false, "before"},
+		{"dev", true, "after"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := testTemplates("before")
+			tmpls, err := NewTemplates(fsys, tt.dev)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Edit the template after it was parsed
+			fsys["home.html"].Data = []byte(`{{define "content"}}after{{end}}`)
+
+			var b strings.Builder
+			if err := tmpls.Execute(&b, "home.html", nil); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("got %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplatesErrors(t *testing.T) {
+	if _, err := NewTemplates(testTemplates("{{.Missing"), false); err == nil {
+		t.Error("got no error for an invalid template")
+	}
+
+	tmpls, err := NewTemplates(testTemplates("home"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tmpls.Execute(&b, "missing.html", nil); err == nil {
+		t.Error("got no error for an unknown page")
+	}
+}
+
+// brokenWriter is a response writer whose client went away
+type brokenWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w brokenWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestTemplatesRenderWriteError(t *testing.T) {
+	tmpls, err := NewTemplates(testTemplates("home"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := brokenWriter{httptest.NewRecorder()}
+	if err := tmpls.Render(w, http.StatusOK, "home.html", nil); err != nil {
+		t.Errorf("got error %v for a failed write, want none", err)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+}
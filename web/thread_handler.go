@@ -15,26 +15,28 @@ import (
 type ThreadHandler struct {
 	store    goreddit.Store
 	sessions *scs.SessionManager
+	errs     *ErrorHandler
 }
 
 // List returns a webpage with the list of all Threads
 func (h *ThreadHandler) List() http.HandlerFunc {
 	type data struct {
 		SessionData
-		Threads []goreddit.Thread
+		Threads    []goreddit.Thread
+		Pagination pagination
 	}
 
-	tmpl := template.Must(template.ParseFiles("templates/layout.html", "templates/threads.html"))
 	return func(w http.ResponseWriter, r *http.Request) {
-		tt, err := h.store.Threads()
+		tt, info, err := h.store.Threads(r.Context(), pageFromQuery(r))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.errs.Error(w, r, err)
 			return
 		}
 
-		tmpl.Execute(w, data{
+		h.errs.Render(w, r, "threads.html", data{
 			SessionData: GetSessionData(r.Context(), h.sessions),
 			Threads:     tt,
+			Pagination:  newPagination(r, info),
 		})
 	}
 }
@@ -45,9 +47,9 @@ func (h *ThreadHandler) Create() http.HandlerFunc {
 		SessionData
 		CSRF template.HTML
 	}
-	tmpl := template.Must(template.ParseFiles("templates/layout.html", "templates/thread_create.html"))
+
 	return func(w http.ResponseWriter, r *http.Request) {
-		tmpl.Execute(w, data{
+		h.errs.Render(w, r, "thread_create.html", data{
 			SessionData: GetSessionData(r.Context(), h.sessions),
 			CSRF:        csrf.TemplateField(r),
 		})
@@ -58,38 +60,71 @@ func (h *ThreadHandler) Create() http.HandlerFunc {
 func (h *ThreadHandler) Show() http.HandlerFunc {
 	type data struct {
 		SessionData
-		CSRF   template.HTML
-		Thread goreddit.Thread
-		Posts  []goreddit.Post
+		CSRF       template.HTML
+		Sort       goreddit.PostSort
+		Thread     goreddit.Thread
+		Posts      []goreddit.Post
+		Votes      map[uuid.UUID]int
+		Pagination pagination
+		Moderators []goreddit.User
+		Banned     []goreddit.User
 	}
 
-	tmpl := template.Must(template.ParseFiles("templates/layout.html", "templates/thread.html"))
 	return func(w http.ResponseWriter, r *http.Request) {
 		idStr := chi.URLParam(r, "id")
 		// convert idStr to UUID first
 		id, err := uuid.Parse(idStr)
 		if err != nil {
-			http.NotFound(w, r)
+			h.errs.NotFound(w, r)
 			return
 		}
 
-		t, err := h.store.Thread(id)
+		t, err := h.store.Thread(r.Context(), id)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.errs.Error(w, r, err)
 			return
 		}
 
-		pp, err := h.store.PostsByThread(t.ID)
+		sort := postSortFromQuery(r)
+
+		pp, info, err := h.store.PostsByThread(r.Context(), t.ID, sort, pageFromQuery(r))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.errs.Error(w, r, err)
 			return
 		}
 
-		tmpl.Execute(w, data{
+		votes, err := userPostVotes(r.Context(), h.store, pp)
+		if err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		moderators, err := h.store.Moderators(r.Context(), t.ID)
+		if err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		// Only the users who may lift bans see who is banned
+		var banned []goreddit.User
+		if roleFromContext(r.Context()).Can(goreddit.PermBanUser) {
+			banned, err = h.store.BannedUsers(r.Context(), t.ID)
+			if err != nil {
+				h.errs.Error(w, r, err)
+				return
+			}
+		}
+
+		h.errs.Render(w, r, "thread.html", data{
 			SessionData: GetSessionData(r.Context(), h.sessions),
 			CSRF:        csrf.TemplateField(r),
+			Sort:        sort,
 			Thread:      t,
 			Posts:       pp,
+			Votes:       votes,
+			Pagination:  newPagination(r, info),
+			Moderators:  moderators,
+			Banned:      banned,
 		})
 	}
 }
@@ -97,15 +132,25 @@ func (h *ThreadHandler) Show() http.HandlerFunc {
 // Store saves the newly created thread to database
 func (h *ThreadHandler) Store() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		title := r.FormValue("title")
-		description := r.FormValue("description")
+		form := CreateThreadForm{
+			Title:       r.FormValue("title"),
+			Description: r.FormValue("description"),
+		}
+		if !form.Validate() {
+			h.sessions.Put(r.Context(), "form", form)
+			http.Redirect(w, r, r.Referer(), http.StatusFound)
+			return
+		}
 
-		if err := h.store.CreateThread(&goreddit.Thread{
+		user, _ := userFromContext(r.Context())
+
+		if err := h.store.CreateThread(r.Context(), &goreddit.Thread{
 			ID:          uuid.New(),
-			Title:       title,
-			Description: description,
+			UserID:      uuid.NullUUID{UUID: user.ID, Valid: true},
+			Title:       form.Title,
+			Description: form.Description,
 		}); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.errs.Error(w, r, err)
 			return
 		}
 
@@ -115,23 +160,111 @@ func (h *ThreadHandler) Store() http.HandlerFunc {
 	}
 }
 
-// Delete deletes a thread based on its id
+// Edit leads to the page for editing a thread
+func (h *ThreadHandler) Edit() http.HandlerFunc {
+	type data struct {
+		SessionData
+		CSRF   template.HTML
+		Thread goreddit.Thread
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, ok := h.modifiableThread(w, r)
+		if !ok {
+			return
+		}
+
+		h.errs.Render(w, r, "thread_edit.html", data{
+			SessionData: GetSessionData(r.Context(), h.sessions),
+			CSRF:        csrf.TemplateField(r),
+			Thread:      t,
+		})
+	}
+}
+
+// Update saves the changes to an edited thread to database
+func (h *ThreadHandler) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, ok := h.modifiableThread(w, r)
+		if !ok {
+			return
+		}
+
+		form := CreateThreadForm{
+			Title:       r.FormValue("title"),
+			Description: r.FormValue("description"),
+		}
+		if !form.Validate() {
+			h.sessions.Put(r.Context(), "form", form)
+			http.Redirect(w, r, r.URL.Path, http.StatusFound)
+			return
+		}
+
+		t.Title = form.Title
+		t.Description = form.Description
+
+		if err := h.store.UpdateThread(r.Context(), &t); err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		h.sessions.Put(r.Context(), "flash", "Your thread has been updated.")
+
+		http.Redirect(w, r, "/threads/"+t.ID.String(), http.StatusFound)
+	}
+}
+
+// modifiableThread gets the thread of the request, writing an error response
+// if it does not exist or the logged in user may not modify it
+func (h *ThreadHandler) modifiableThread(w http.ResponseWriter, r *http.Request) (goreddit.Thread, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.errs.NotFound(w, r)
+		return goreddit.Thread{}, false
+	}
+
+	t, err := h.store.Thread(r.Context(), id)
+	if err != nil {
+		h.errs.Error(w, r, err)
+		return goreddit.Thread{}, false
+	}
+
+	if !canModify(r.Context(), t.UserID) {
+		http.Error(w, "You may only change your own threads", http.StatusForbidden)
+		return goreddit.Thread{}, false
+	}
+	return t, true
+}
+
+// Delete deletes a thread based on its id, which its author and site admins
+// may do
 func (h *ThreadHandler) Delete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		idStr := chi.URLParam(r, "id")
 
 		id, err := uuid.Parse(idStr)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.errs.NotFound(w, r)
+			return
+		}
+
+		t, err := h.store.Thread(r.Context(), id)
+		if err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		if !canChange(r.Context(), t.UserID, goreddit.PermDeleteThread) {
+			http.Error(w, "You may only delete your own threads", http.StatusForbidden)
 			return
 		}
 
-		if err := h.store.DeleteThread(id); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if err := h.store.DeleteThread(r.Context(), t.ID); err != nil {
+			h.errs.Error(w, r, err)
 			return
 		}
 
-		h.sessions.Put(r.Context(), "flash", "Your thread has been deleted.")
+		h.sessions.Put(r.Context(), "flash", "The thread has been deleted.")
 
 		http.Redirect(w, r, "/threads", http.StatusFound)
 	}
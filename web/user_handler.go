@@ -0,0 +1,161 @@
+package web
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/google/uuid"
+	"github.com/gorilla/csrf"
+	"github.com/nahuakang/goreddit"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// UserHandler handles users
+type UserHandler struct {
+	store    goreddit.Store
+	sessions *scs.SessionManager
+	errs     *ErrorHandler
+}
+
+// Register leads to the page for registering new users
+func (h *UserHandler) Register() http.HandlerFunc {
+	type data struct {
+		SessionData
+		CSRF template.HTML
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.errs.Render(w, r, "user_register.html", data{
+			SessionData: GetSessionData(r.Context(), h.sessions),
+			CSRF:        csrf.TemplateField(r),
+		})
+	}
+}
+
+// RegisterSubmit saves the newly registered user to database
+func (h *UserHandler) RegisterSubmit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		form := RegisterForm{
+			Username:      strings.TrimSpace(r.FormValue("username")),
+			Password:      r.FormValue("password"),
+			UsernameTaken: false,
+		}
+		if _, err := h.store.UserByUsername(r.Context(), form.Username); err == nil {
+			form.UsernameTaken = true
+		}
+		if !form.Validate() {
+			// The session is stored in the database, which must not see the password
+			form.Password = ""
+			h.sessions.Put(r.Context(), "form", form)
+			http.Redirect(w, r, r.Referer(), http.StatusFound)
+			return
+		}
+
+		password, err := bcrypt.GenerateFromPassword([]byte(form.Password), bcrypt.DefaultCost)
+		if err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		if err := h.store.CreateUser(r.Context(), &goreddit.User{
+			ID:       uuid.New(),
+			Username: form.Username,
+			Password: string(password),
+		}); errors.Is(err, goreddit.ErrConflict) {
+			// Someone else registered the username in the meantime
+			form.UsernameTaken = true
+			form.Validate()
+			form.Password = ""
+			h.sessions.Put(r.Context(), "form", form)
+			http.Redirect(w, r, r.Referer(), http.StatusFound)
+			return
+		} else if err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		h.sessions.Put(r.Context(), "flash", "Your registration was successful. Please log in.")
+
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}
+}
+
+// Login leads to the page for logging in
+func (h *UserHandler) Login() http.HandlerFunc {
+	type data struct {
+		SessionData
+		CSRF template.HTML
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.errs.Render(w, r, "user_login.html", data{
+			SessionData: GetSessionData(r.Context(), h.sessions),
+			CSRF:        csrf.TemplateField(r),
+		})
+	}
+}
+
+// dummyPasswordHash is a bcrypt hash with the default cost that no password
+// matches
+const dummyPasswordHash = "$2a$10$98noed0OjRwy/6g1kGQNiOQXRz5DCtjwfTZu2ZeimTfuX2qInsWau"
+
+// checkPassword reports whether password is the password of user, which was
+// looked up with the error err. Unknown users are compared with a dummy hash,
+// so that the response time does not tell whether a username is registered.
+func checkPassword(user goreddit.User, err error, password string) bool {
+	hash := dummyPasswordHash
+	if err == nil {
+		hash = user.Password
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil && err == nil
+}
+
+// LoginSubmit checks the credentials and stores the user id in the session
+func (h *UserHandler) LoginSubmit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		form := LoginForm{
+			Username:             strings.TrimSpace(r.FormValue("username")),
+			Password:             r.FormValue("password"),
+			IncorrectCredentials: false,
+		}
+
+		user, err := h.store.UserByUsername(r.Context(), form.Username)
+		form.IncorrectCredentials = !checkPassword(user, err, form.Password)
+		if !form.Validate() {
+			form.Password = ""
+			h.sessions.Put(r.Context(), "form", form)
+			http.Redirect(w, r, r.Referer(), http.StatusFound)
+			return
+		}
+
+		// Renew the session token to prevent session fixation
+		if err := h.sessions.RenewToken(r.Context()); err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		h.sessions.Put(r.Context(), "user_id", user.ID)
+		h.sessions.Put(r.Context(), "flash", "You have been logged in successfully.")
+
+		http.Redirect(w, r, "/", http.StatusFound)
+	}
+}
+
+// Logout removes the user id from the session. It is a POST request with a
+// CSRF token, so that other sites cannot log users out.
+func (h *UserHandler) Logout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.sessions.RenewToken(r.Context()); err != nil {
+			h.errs.Error(w, r, err)
+			return
+		}
+
+		h.sessions.Remove(r.Context(), "user_id")
+		h.sessions.Put(r.Context(), "flash", "You have been logged out successfully.")
+
+		http.Redirect(w, r, "/", http.StatusFound)
+	}
+}